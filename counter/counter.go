@@ -0,0 +1,127 @@
+// Package counter tallies keys for programs that embed the distribution's
+// counting and rendering, such as services that dump the distribution of
+// their request paths. A Counter may be added to from many goroutines at once,
+// and a Snapshot of it rendered by a histogram.Histogram as the command line
+// tool would print it.
+package counter
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Counter is a tally of keys that is safe for concurrent use. The zero value
+// is an empty Counter ready to use.
+type Counter struct {
+	mu     sync.Mutex
+	counts map[string]float64
+}
+
+// Snapshot is a copy of the counts of a Counter at one moment, which later
+// changes to the Counter do not touch
+type Snapshot map[string]float64
+
+// Entry is a key and its count
+type Entry struct {
+	Key   string
+	Value float64
+}
+
+// New returns an empty Counter
+func New() *Counter {
+	return &Counter{counts: make(map[string]float64)}
+}
+
+// Add counts key n more times, or by n if it is a weight such as a number of
+// bytes
+func (c *Counter) Add(key string, n float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[string]float64)
+	}
+	c.counts[key] += n
+}
+
+// Merge adds the counts of other to c, as when each worker of a service keeps
+// its own Counter
+func (c *Counter) Merge(other *Counter) {
+	// copied first so that the two locks are never held together, and merging
+	// a Counter into itself doubles it
+	snapshot := other.Snapshot()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[string]float64, len(snapshot))
+	}
+	for k, v := range snapshot {
+		c.counts[k] += v
+	}
+}
+
+// TopN returns the n keys with the biggest counts, biggest first, and keys
+// with equal counts in order. A count's size is what matters, not its sign.
+// If n is less than 0 every key is returned.
+func (c *Counter) TopN(n int) []Entry {
+	snapshot := c.Snapshot()
+	entries := make([]Entry, 0, len(snapshot))
+	for k, v := range snapshot {
+		entries = append(entries, Entry{k, v})
+	}
+
+	sort.Sort(byMagnitude(entries))
+	if n >= 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// Reset forgets every count
+func (c *Counter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts = make(map[string]float64)
+}
+
+// Snapshot returns a copy of the counts
+func (c *Counter) Snapshot() Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := make(Snapshot, len(c.counts))
+	for k, v := range c.counts {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+// Len returns the number of distinct keys counted
+func (c *Counter) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.counts)
+}
+
+// byMagnitude orders entries biggest first by the size of their values, then
+// by key
+type byMagnitude []Entry
+
+func (b byMagnitude) Len() int { return len(b) }
+
+func (b byMagnitude) Less(i, j int) bool {
+	x, y := math.Abs(b[i].Value), math.Abs(b[j].Value)
+	if x == y {
+		return b[i].Key < b[j].Key
+	}
+	return x > y
+}
+
+func (b byMagnitude) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
@@ -0,0 +1,109 @@
+package counter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestCounter_Add(t *testing.T) {
+	var c Counter
+	c.Add("a", 1)
+	c.Add("b", 2.5)
+	c.Add("a", 1)
+
+	expected := Snapshot{"a": 2, "b": 2.5}
+	if actual := c.Snapshot(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Snapshot incorrect: expected %v; actual %v", expected, actual)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len incorrect: expected %v; actual %v", 2, c.Len())
+	}
+}
+
+func TestCounter_Concurrent(t *testing.T) {
+	c := New()
+	other := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Add(fmt.Sprintf("key%d", j%4), 1)
+				if j%100 == 0 {
+					c.Snapshot()
+					other.Merge(c)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	expected := Snapshot{"key0": 2000, "key1": 2000, "key2": 2000, "key3": 2000}
+	if actual := c.Snapshot(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Snapshot incorrect: expected %v; actual %v", expected, actual)
+	}
+}
+
+func TestCounter_Merge(t *testing.T) {
+	c := New()
+	c.Add("a", 1)
+	other := New()
+	other.Add("a", 2)
+	other.Add("b", 3)
+
+	c.Merge(other)
+	expected := Snapshot{"a": 3, "b": 3}
+	if actual := c.Snapshot(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Merge incorrect: expected %v; actual %v", expected, actual)
+	}
+
+	c.Merge(c)
+	expected = Snapshot{"a": 6, "b": 6}
+	if actual := c.Snapshot(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Merge with itself incorrect: expected %v; actual %v", expected, actual)
+	}
+}
+
+func TestCounter_TopN(t *testing.T) {
+	c := New()
+	for k, v := range map[string]float64{"a": 1, "b": 5, "c": -7, "d": 5} {
+		c.Add(k, v)
+	}
+
+	testCases := []struct {
+		n        int
+		expected []Entry
+	}{
+		{0, []Entry{}},
+		{2, []Entry{{"c", -7}, {"b", 5}}},
+		{3, []Entry{{"c", -7}, {"b", 5}, {"d", 5}}},
+		{10, []Entry{{"c", -7}, {"b", 5}, {"d", 5}, {"a", 1}}},
+		{-1, []Entry{{"c", -7}, {"b", 5}, {"d", 5}, {"a", 1}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.n), func(t *testing.T) {
+			actual := c.TopN(tc.n)
+			if fmt.Sprint(actual) != fmt.Sprint(tc.expected) {
+				t.Errorf("TopN incorrect: expected %v; actual %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCounter_Reset(t *testing.T) {
+	c := New()
+	c.Add("a", 1)
+	snapshot := c.Snapshot()
+	c.Reset()
+
+	if c.Len() != 0 {
+		t.Errorf("Len incorrect: expected %v; actual %v", 0, c.Len())
+	}
+	if snapshot["a"] != 1 {
+		t.Errorf("Snapshot changed by Reset: expected %v; actual %v", 1, snapshot["a"])
+	}
+}
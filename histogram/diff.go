@@ -0,0 +1,144 @@
+package histogram
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// WriteDiff renders the difference between two distributions of the same
+// keys, before and after. Each row shows a key's count in both, the absolute
+// and percentage change, and a bar drawn left of the centre axis for a fall
+// and right of it for a rise, all on the same scale. Keys that are only in one
+// of the inputs are marked "new" or "gone". The keys that changed most are
+// shown.
+func (h *Histogram) WriteDiff(writer io.Writer, before map[string]float64, after map[string]float64) {
+	changes := make(map[string]float64)
+	for k, a := range before {
+		changes[k] = absDiff(a, after[k])
+	}
+	for k, b := range after {
+		if _, ok := before[k]; !ok {
+			changes[k] = b
+		}
+	}
+
+	l := h.newLayout(changes, int(h.height))
+	h.writeStats(h.runStats(l))
+
+	beforeWidth, afterWidth, changeWidth, pctWidth := len("A"), len("B"), len("Change"), len("(Pct)")
+	for _, p := range l.pairs {
+		a, b := before[p.Key], after[p.Key]
+		beforeWidth = maxInt(beforeWidth, len(formatValue(a)))
+		afterWidth = maxInt(afterWidth, len(formatValue(b)))
+		changeWidth = maxInt(changeWidth, len(signedDiff(a, b)))
+		pctWidth = maxInt(pctWidth, len(diffPct(before, after, p.Key)))
+	}
+
+	histWidth := int(h.width) - (l.maxTokenLen + 1) - (beforeWidth + 1) - (afterWidth + 1) - (changeWidth + 1) - (pctWidth + 1) - 1
+	halfWidth := histWidth / 2
+	if halfWidth < 1 {
+		// long keys or a narrow terminal leave no room, but the axis still goes in
+		halfWidth = 1
+	}
+
+	io.WriteString(h.header, Rjust("Key", l.maxTokenLen))
+	io.WriteString(h.header, "|")
+	io.WriteString(h.header, Rjust("A", beforeWidth))
+	io.WriteString(h.header, " ")
+	io.WriteString(h.header, Rjust("B", afterWidth))
+	io.WriteString(h.header, " ")
+	io.WriteString(h.header, Rjust("Change", changeWidth))
+	io.WriteString(h.header, " ")
+	io.WriteString(h.header, Rjust("(Pct)", pctWidth))
+	io.WriteString(h.header, " ")
+	io.WriteString(h.header, Rjust("-", halfWidth))
+	io.WriteString(h.header, "|+")
+	io.WriteString(h.header, h.keyColor)
+	io.WriteString(h.header, "\n")
+
+	for _, p := range l.pairs {
+		a, b := before[p.Key], after[p.Key]
+
+		io.WriteString(writer, Rjust(p.Key, l.maxTokenLen))
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, "|")
+		io.WriteString(writer, h.ctColor)
+		io.WriteString(writer, Rjust(formatValue(a), beforeWidth))
+		io.WriteString(writer, " ")
+		io.WriteString(writer, Rjust(formatValue(b), afterWidth))
+		io.WriteString(writer, " ")
+		io.WriteString(writer, Rjust(signedDiff(a, b), changeWidth))
+		io.WriteString(writer, " ")
+		io.WriteString(writer, h.pctColor)
+		io.WriteString(writer, Rjust(diffPct(before, after, p.Key), pctWidth))
+		io.WriteString(writer, " ")
+
+		bar := ""
+		if p.Value > 0 {
+			// HistogramBar ends any non-empty bar with one more character, which has to fit too
+			bar = h.HistogramBar(halfWidth-1, l.maxVal, p.Value)
+		}
+		io.WriteString(writer, h.graphColor)
+		if b < a {
+			io.WriteString(writer, Rjust(reverse(bar), halfWidth))
+			io.WriteString(writer, h.regularColor)
+			io.WriteString(writer, "|")
+		} else {
+			io.WriteString(writer, strings.Repeat(" ", halfWidth))
+			io.WriteString(writer, h.regularColor)
+			io.WriteString(writer, "|")
+			io.WriteString(writer, h.graphColor)
+			io.WriteString(writer, bar)
+		}
+		io.WriteString(writer, h.keyColor)
+		io.WriteString(writer, "\n")
+	}
+	io.WriteString(writer, h.regularColor)
+}
+
+func absDiff(a float64, b float64) float64 {
+	return math.Abs(b - a)
+}
+
+func signedDiff(a float64, b float64) string {
+	if b < a {
+		return "-" + formatValue(a-b)
+	}
+	return "+" + formatValue(b-a)
+}
+
+// diffPct formats the change in key as a percentage of its count before, or
+// flags keys that are only present on one side
+func diffPct(before map[string]float64, after map[string]float64, key string) string {
+	a, inBefore := before[key]
+	_, inAfter := after[key]
+	if !inBefore {
+		return "(new)"
+	}
+	if !inAfter {
+		return "(gone)"
+	}
+	if a == 0 {
+		return "(-)"
+	}
+	return fmt.Sprintf("(%+2.2f%%)", (after[key]-a)/math.Abs(a)*100.0)
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// reverse returns s with its characters in the opposite order, turning a bar
+// that grows rightwards into one that grows leftwards
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
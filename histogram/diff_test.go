@@ -0,0 +1,55 @@
+package histogram
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHistogram_WriteDiff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		before   map[string]float64
+		after    map[string]float64
+		expected string
+	}{
+		{
+			name:     "Empty PairLists",
+			args:     []string{RC_FILE, "--width=40"},
+			before:   make(map[string]float64),
+			after:    make(map[string]float64),
+			expected: "",
+		},
+		{
+			name:   "Rises, falls, new and gone",
+			args:   []string{RC_FILE, "--width=40"},
+			before: map[string]float64{"a": 4, "b": 4, "c": 2},
+			after:  map[string]float64{"a": 8, "b": 2, "d": 1},
+			expected: "a|4 8     +4 (+100.00%)        |-------\n" +
+				"c|2 0     -2     (gone)    ----|\n" +
+				"b|4 2     -2  (-50.00%)    ----|\n" +
+				"d|0 1     +1      (new)        |--\n",
+		},
+		{
+			name:     "Keys too long for any bar",
+			args:     []string{RC_FILE, "--width=30"},
+			before:   map[string]float64{"a-key-of-twenty-five-char": 4},
+			after:    map[string]float64{"a-key-of-twenty-five-char": 2},
+			expected: "a-key-of-twenty-five-char|4 2     -2 (-50.00%)  |\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSettings(t, tc.args)
+			h := NewHistogram(s)
+			buf := new(bytes.Buffer)
+
+			h.WriteDiff(buf, tc.before, tc.after)
+
+			if buf.String() != tc.expected {
+				t.Errorf("WriteDiff incorrect: expected\n%s\nactual\n%s", tc.expected, buf.String())
+			}
+		})
+	}
+}
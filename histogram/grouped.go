@@ -0,0 +1,87 @@
+package histogram
+
+import (
+	"io"
+	"strings"
+)
+
+// WriteGrouped renders a group of bars for each of the highest-valued keys,
+// one bar per series in the order given, all on the same scale. pairCounts
+// maps key to series name to value. Values below zero are rejected.
+func (h *Histogram) WriteGrouped(writer io.Writer, pairCounts map[string]map[string]float64, series []string) error {
+	if err := unsignedPairs("a grouped histogram", pairCounts); err != nil {
+		return err
+	}
+
+	totals := make(map[string]float64)
+	for key, row := range pairCounts {
+		for _, v := range row {
+			totals[key] += v
+		}
+	}
+
+	// every key takes a line for each series
+	limit := int(h.height)
+	if len(series) > 0 {
+		limit /= len(series)
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	l := h.newLayout(totals, limit)
+	h.writeStats(h.runStats(l))
+
+	// the bars are scaled to the biggest value shown
+	maxVal := 0.0
+	valueWidth := len("Ct")
+	for _, p := range l.pairs {
+		for _, name := range series {
+			v := pairCounts[p.Key][name]
+			if v > maxVal {
+				maxVal = v
+			}
+			if w := len(formatValue(v)); w > valueWidth {
+				valueWidth = w
+			}
+		}
+	}
+	histWidth := int(h.width) - (l.maxTokenLen + 1) - (valueWidth + 1) - 1
+	if histWidth < 0 {
+		histWidth = 0
+	}
+
+	io.WriteString(h.header, Rjust("Key", l.maxTokenLen))
+	io.WriteString(h.header, "|")
+	io.WriteString(h.header, Ljust("Ct", valueWidth))
+	h.writeLegend(h.header, series)
+
+	for _, p := range l.pairs {
+		for i, name := range series {
+			v := pairCounts[p.Key][name]
+
+			key := ""
+			if i == 0 {
+				key = p.Key
+			}
+			io.WriteString(writer, h.keyColor)
+			io.WriteString(writer, Rjust(key, l.maxTokenLen))
+			io.WriteString(writer, h.regularColor)
+			io.WriteString(writer, "|")
+			io.WriteString(writer, h.ctColor)
+			io.WriteString(writer, Rjust(formatValue(v), valueWidth))
+			io.WriteString(writer, " ")
+
+			length := int(h.barFraction(maxVal, v) * float64(histWidth))
+			if length == 0 && v != 0 && histWidth > 0 {
+				length = 1
+			}
+			colour, char := h.seriesStyle(i)
+			io.WriteString(writer, colour)
+			io.WriteString(writer, strings.Repeat(char, length))
+			io.WriteString(writer, h.regularColor)
+			io.WriteString(writer, "\n")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package histogram
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHistogram_WriteGrouped(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		counts   map[string]map[string]float64
+		series   []string
+		expected string
+	}{
+		{
+			name:     "Empty PairCounts",
+			args:     []string{RC_FILE, "--width=20"},
+			counts:   make(map[string]map[string]float64),
+			series:   []string{},
+			expected: "",
+		},
+		{
+			name: "Two series",
+			args: []string{RC_FILE, "--width=20"},
+			counts: map[string]map[string]float64{
+				"/a": {"requests": 12, "errors": 1},
+				"/b": {"requests": 6, "errors": 3},
+			},
+			series: []string{"requests", "errors"},
+			expected: "/a|12 █████████████\n" +
+				"  | 1 ▓\n" +
+				"/b| 6 ██████\n" +
+				"  | 3 ▓▓▓\n",
+		},
+		{
+			name: "Scaled to the keys shown",
+			args: []string{RC_FILE, "--width=20", "--height=2"},
+			counts: map[string]map[string]float64{
+				"/a": {"requests": 10, "errors": 10},
+				"/b": {"requests": 15},
+			},
+			series:   []string{"requests", "errors"},
+			expected: "/a|10 █████████████\n  |10 ▓▓▓▓▓▓▓▓▓▓▓▓▓\n",
+		},
+		{
+			name: "No room for bars",
+			args: []string{RC_FILE, "--width=5"},
+			counts: map[string]map[string]float64{
+				"/a": {"requests": 12, "errors": 1},
+			},
+			series:   []string{"requests", "errors"},
+			expected: "/a|12 \n  | 1 \n",
+		},
+		{
+			name: "Height limits keys",
+			args: []string{RC_FILE, "--width=20", "--height=3"},
+			counts: map[string]map[string]float64{
+				"/a": {"requests": 12, "errors": 1},
+				"/b": {"requests": 6, "errors": 3},
+			},
+			series: []string{"requests", "errors"},
+			expected: "/a|12 █████████████\n" +
+				"  | 1 ▓\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSettings(t, tc.args)
+			h := NewHistogram(s)
+			buf := new(bytes.Buffer)
+
+			if err := h.WriteGrouped(buf, tc.counts, tc.series); err != nil {
+				t.Fatalf("WriteGrouped returned an error: %s", err)
+			}
+
+			if buf.String() != tc.expected {
+				t.Errorf("WriteGrouped incorrect: expected\n%s\nactual\n%s", tc.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package histogram
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// heatShades are the cells of an uncoloured heatmap, lightest first
+var heatShades = []string{"░", "▒", "▓", "█"}
+
+// heatRamp are the ANSI 256-colour backgrounds of a coloured heatmap, coolest
+// first
+var heatRamp = []int{22, 28, 34, 40, 46, 82, 118, 154, 190, 226}
+
+// maxHeatmapLabelRows is how long the rotated column labels may be, which is
+// long enough for a full timestamp
+const maxHeatmapLabelRows = 20
+
+// WriteHeatmap renders a matrix with a row for each of the highest-counted
+// primary keys and a column for each secondary key, shading each cell by its
+// count. pairCounts maps primary key to secondary key to count, and totals
+// each primary key to the sum of its counts. columns, if given, are the
+// secondary keys to show in order, such as every time bucket from the earliest
+// to the latest, and the last of them are kept if they do not all fit.
+// Otherwise the highest-counted secondary keys are shown in key order. Counts
+// below zero are rejected.
+func (h *Histogram) WriteHeatmap(writer io.Writer, pairCounts map[string]map[string]float64, totals map[string]float64, columns []string) error {
+	if err := unsignedPairs("a heatmap", pairCounts); err != nil {
+		return err
+	}
+
+	l := h.newLayout(totals, int(h.height))
+	h.writeStats(h.runStats(l))
+
+	if len(l.pairs) == 0 {
+		return nil
+	}
+
+	columnTotals := make(map[string]float64)
+	for _, p := range l.pairs {
+		for secondary, v := range pairCounts[p.Key] {
+			columnTotals[secondary] += v
+		}
+	}
+	available := int(h.width) - (l.maxTokenLen + 1) - (l.maxValueWidth + 2)
+	if available < 1 {
+		available = 1
+	}
+
+	var shown pairlist
+	if columns != nil {
+		if len(columns) > available {
+			columns = columns[len(columns)-available:]
+		}
+		for _, c := range columns {
+			shown = append(shown, pair{c, columnTotals[c]})
+		}
+	} else {
+		shown = NewPairList(columnTotals)
+		sort.Sort(sort.Reverse(shown))
+		if len(shown) > available {
+			shown = shown[:available]
+		}
+		sort.Sort(byKey{shown})
+	}
+
+	maxCell := 0.0
+	for _, p := range l.pairs {
+		for _, c := range shown {
+			if v := pairCounts[p.Key][c.Key]; v > maxCell {
+				maxCell = v
+			}
+		}
+	}
+
+	for _, p := range l.pairs {
+		io.WriteString(writer, h.keyColor)
+		io.WriteString(writer, Rjust(p.Key, l.maxTokenLen))
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, "|")
+		io.WriteString(writer, h.graphColor)
+		for _, c := range shown {
+			io.WriteString(writer, h.heatCell(maxCell, pairCounts[p.Key][c.Key]))
+		}
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, "| ")
+		io.WriteString(writer, h.ctColor)
+		io.WriteString(writer, formatValue(p.Value))
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, "\n")
+	}
+
+	indent := strings.Repeat(" ", l.maxTokenLen+1)
+	for _, line := range h.axisLabels(shown, 1, maxHeatmapLabelRows) {
+		io.WriteString(writer, indent)
+		io.WriteString(writer, h.keyColor)
+		io.WriteString(writer, line)
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, "\n")
+	}
+
+	io.WriteString(writer, indent)
+	io.WriteString(writer, "scale: 1 ")
+	io.WriteString(writer, h.graphColor)
+	io.WriteString(writer, h.heatScale())
+	io.WriteString(writer, h.regularColor)
+	io.WriteString(writer, " "+formatValue(maxCell))
+	if h.s.Logarithmic {
+		io.WriteString(writer, " (logarithmic)")
+	}
+	io.WriteString(writer, "\n")
+
+	return nil
+}
+
+// heatScale returns every shade heatCell uses, lightest first
+func (h *Histogram) heatScale() string {
+	if h.s.ColourisedOutput {
+		scale := ""
+		for _, c := range heatRamp {
+			scale += fmt.Sprintf("\u001b[48;5;%dm \u001b[49m", c)
+		}
+		return scale
+	}
+	return strings.Join(heatShades, "")
+}
+
+// heatCell returns a single character cell shaded for v out of maxVal, using
+// background colours when the output is colourised and block shading
+// otherwise. Empty cells are left blank.
+func (h *Histogram) heatCell(maxVal float64, v float64) string {
+	if v == 0 {
+		return " "
+	}
+
+	fraction := h.barFraction(maxVal, v)
+	if h.s.ColourisedOutput {
+		return fmt.Sprintf("\u001b[48;5;%dm \u001b[49m", heatRamp[shadeIndex(fraction, len(heatRamp))])
+	}
+	return heatShades[shadeIndex(fraction, len(heatShades))]
+}
+
+// shadeIndex maps a fraction in (0, 1] to one of n shades
+func shadeIndex(fraction float64, n int) int {
+	i := int(math.Ceil(fraction*float64(n))) - 1
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
@@ -0,0 +1,101 @@
+package histogram
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHistogram_WriteHeatmap(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		counts   map[string]map[string]float64
+		columns  []string
+		expected string
+	}{
+		{
+			name:     "Empty PairCounts",
+			args:     []string{RC_FILE, WIDTH},
+			counts:   make(map[string]map[string]float64),
+			expected: "",
+		},
+		{
+			name: "Two rows",
+			args: []string{RC_FILE, WIDTH},
+			counts: map[string]map[string]float64{
+				"/a": {"1": 8, "2": 2},
+				"/b": {"2": 4, "3": 1},
+			},
+			expected: "/a|█░ | 10\n" +
+				"/b| ▒░| 5\n" +
+				"   123\n" +
+				"   scale: 1 ░▒▓█ 8\n",
+		},
+		{
+			name: "Columns limited to width",
+			args: []string{RC_FILE, "--width=9"},
+			counts: map[string]map[string]float64{
+				"/a": {"1": 8, "2": 2, "3": 1},
+			},
+			expected: "/a|█░| 11\n" +
+				"   12\n" +
+				"   scale: 1 ░▒▓█ 8\n",
+		},
+		{
+			name: "Every time bucket",
+			args: []string{RC_FILE, WIDTH},
+			counts: map[string]map[string]float64{
+				"/a": {"12:00": 4, "12:05": 2, "12:20": 1},
+			},
+			columns: []string{"12:00", "12:05", "12:10", "12:15", "12:20"},
+			expected: "/a|█▒  ░| 7\n" +
+				"   11111\n" +
+				"   22222\n" +
+				"   :::::\n" +
+				"   00112\n" +
+				"   05050\n" +
+				"   scale: 1 ░▒▓█ 4\n",
+		},
+		{
+			name: "Latest time buckets limited to width",
+			args: []string{RC_FILE, "--width=10"},
+			counts: map[string]map[string]float64{
+				"/a": {"12:00": 4, "12:05": 2, "12:20": 1},
+			},
+			columns: []string{"12:00", "12:05", "12:10", "12:15", "12:20"},
+			expected: "/a|█  ▒| 7\n" +
+				"   1111\n" +
+				"   2222\n" +
+				"   ::::\n" +
+				"   0112\n" +
+				"   5050\n" +
+				"   scale: 1 ░▒▓█ 2\n",
+		},
+		{
+			name: "Columns limited to width when there is no room",
+			args: []string{RC_FILE, "--width=1"},
+			counts: map[string]map[string]float64{
+				"/a": {"1": 8, "2": 2, "3": 1},
+			},
+			expected: "/a|█| 11\n" +
+				"   1\n" +
+				"   scale: 1 ░▒▓█ 8\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSettings(t, tc.args)
+			h := NewHistogram(s)
+			buf := new(bytes.Buffer)
+
+			if err := h.WriteHeatmap(buf, tc.counts, rowTotals(tc.counts), tc.columns); err != nil {
+				t.Fatalf("WriteHeatmap returned an error: %s", err)
+			}
+
+			if buf.String() != tc.expected {
+				t.Errorf("WriteHeatmap incorrect: expected\n%s\nactual\n%s", tc.expected, buf.String())
+			}
+		})
+	}
+}
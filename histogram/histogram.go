@@ -3,12 +3,16 @@ package histogram
 import (
 	"fmt"
 	"io"
-	"os"
+	"io/ioutil"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/bradfordboyle/go-distribution/counter"
 	"github.com/bradfordboyle/go-distribution/settings"
+	"github.com/bradfordboyle/go-distribution/stats"
 
 	"github.com/dustin/go-humanize"
 )
@@ -22,6 +26,16 @@ type Histogram struct {
 	ctColor      string
 	pctColor     string
 	graphColor   string
+	negColor     string
+	summary      *stats.Summary
+	// distinctError is the relative error of distinct counts that are
+	// estimates, or 0 if they are exact
+	distinctError float64
+	// header and stats are where the column headings and the --verbose
+	// statistics are written
+	header  io.Writer
+	stats   io.Writer
+	runtime time.Duration
 }
 
 func NewHistogram(s *settings.Settings) *Histogram {
@@ -34,83 +48,299 @@ func NewHistogram(s *settings.Settings) *Histogram {
 		ctColor:      s.CtColour,
 		pctColor:     s.PctColour,
 		graphColor:   s.GraphColour,
+		negColor:     s.NegativeColour,
+		header:       ioutil.Discard,
+		stats:        ioutil.Discard,
 	}
 }
 
-func (h *Histogram) WriteHist(writer io.Writer, tokenCounts map[string]uint) {
-	pairlist := NewPairList(tokenCounts)
-	maxTokenLen := 0
-	maxVal := uint(0)
+// SetWriters sets where the column headings and the --verbose statistics are
+// written, apart from the bars written to the writer given to each Write
+// method. Either may be nil to leave it out, as it is by default. The command
+// line tool writes both to stderr so that stdout can be piped on.
+func (h *Histogram) SetWriters(header io.Writer, stats io.Writer) {
+	if header == nil {
+		header = ioutil.Discard
+	}
+	if stats == nil {
+		stats = ioutil.Discard
+	}
+	h.header, h.stats = header, stats
+}
+
+// SetRuntime records how long the input took to read and count, to be
+// reported with --verbose
+func (h *Histogram) SetRuntime(d time.Duration) {
+	h.runtime = d
+}
+
+// SetSummary attaches the summary statistics of numeric input, to be reported
+// with --verbose and in machine-readable output
+func (h *Histogram) SetSummary(summary *stats.Summary) {
+	h.summary = summary
+}
 
-	maxValueWidth := 0
-	maxPctWidth := 0
+// SetDistinctError records the relative error of distinct counts that are
+// estimates, to be reported with --verbose
+func (h *Histogram) SetDistinctError(e float64) {
+	h.distinctError = e
+}
 
-	sort.Sort(sort.Reverse(pairlist))
-	totalValue := pairlist.TotalValues()
+// layout holds the measurements shared by every renderer: the rows to draw
+// and the widths of the columns they are drawn in.
+type layout struct {
+	pairs         pairlist
+	keys          int
+	totalValue    float64
+	maxVal        float64
+	negative      bool
+	showPct       bool
+	maxTokenLen   int
+	maxValueWidth int
+	maxPctWidth   int
+	histWidth     int
+}
 
-	for i, p := range pairlist {
+// newLayout measures the limit pairs in tokenCounts with the biggest values,
+// negative or positive, ordered as requested by --sort. Bins are the
+// exception: the first limit of them are shown, so that the range they cover
+// has no gaps. maxVal is the size of the biggest value shown.
+func (h *Histogram) newLayout(tokenCounts map[string]float64, limit int) *layout {
+	pairlist := NewPairList(tokenCounts)
+	l := &layout{keys: pairlist.Len(), totalValue: pairlist.TotalValues(), showPct: h.additive()}
 
-		if i == 0 {
-			maxValueWidth = len(fmt.Sprintf("%d", p.Value))
-			maxPctWidth = len(fmt.Sprintf("(%2.2f%%)", float64(p.Value)*1.0/float64(totalValue)*100.0))
-		}
+	sort.Sort(sort.Reverse(byMagnitude{pairlist}))
+	if h.s.Binned {
+		sort.Stable(byKey{pairlist})
+	}
+	if pairlist.Len() > limit {
+		pairlist = pairlist[:limit]
+	}
+	if h.s.Sort == "key" {
+		sort.Stable(byKey{pairlist})
+	} else {
+		sort.Stable(sort.Reverse(pairlist))
+	}
+	l.pairs = pairlist
+
+	for _, p := range pairlist {
 
 		tokenLen := len(p.Key)
-		if tokenLen > maxTokenLen {
-			maxTokenLen = tokenLen
+		if tokenLen > l.maxTokenLen {
+			l.maxTokenLen = tokenLen
 		}
 
-		if p.Value > maxVal {
-			maxVal = p.Value
+		if math.Abs(p.Value) > l.maxVal {
+			l.maxVal = math.Abs(p.Value)
+		}
+		if p.Value < 0 {
+			l.negative = true
 		}
 
-		if uint(i) >= h.height-1 {
-			break
+		if w := len(formatValue(p.Value)); w > l.maxValueWidth {
+			l.maxValueWidth = w
+		}
+		if w := len(l.pct(p.Value)); l.showPct && w > l.maxPctWidth {
+			l.maxPctWidth = w
 		}
 	}
 
-	h.s.EndTime = time.Now().UnixNano()
-	totalMillis := float64(h.s.EndTime-h.s.StartTime) / 1e6
+	// the name of an aggregate heads its column; "Ct" has always been left to
+	// overhang narrow counts
+	if h.s.Agg != "" && len(h.s.Agg) > l.maxValueWidth {
+		l.maxValueWidth = len(h.s.Agg)
+	}
+
+	l.histWidth = int(h.width) - (l.maxTokenLen + 1) - (l.maxValueWidth + 1) - 1
+	if l.showPct {
+		l.histWidth -= l.maxPctWidth + 1
+	}
+
+	return l
+}
+
+// percent returns the size of value as a percentage of the total size of all
+// values
+func (l *layout) percent(value float64) float64 {
+	return math.Abs(value) * 1.0 / l.totalValue * 100.0
+}
+
+// pct formats the size of value as a percentage of the total size of all
+// values
+func (l *layout) pct(value float64) string {
+	return fmt.Sprintf("(%2.2f%%)", l.percent(value))
+}
+
+// formatValue writes a value as briefly as possible: whole numbers without a
+// decimal point, and anything else to two decimal places
+func formatValue(v float64) string {
+	return strconv.FormatFloat(math.Floor(v*100+0.5)/100, 'f', -1, 64)
+}
+
+// runStats are the figures reported by --verbose
+type runStats struct {
+	Examined    uint
+	Matched     uint64
+	Keys        int
+	TotalMillis float64
+}
+
+func (h *Histogram) runStats(l *layout) runStats {
+	return runStats{
+		Examined:    h.s.TotalObjects,
+		Matched:     h.s.TotalValues,
+		Keys:        l.keys,
+		TotalMillis: float64(h.runtime) / float64(time.Millisecond),
+	}
+}
+
+func (h *Histogram) writeStats(st runStats) {
 	if h.s.Verbose {
 
-		os.Stderr.WriteString(fmt.Sprintf("tokens/lines examined: %s\n", humanize.Comma(int64(h.s.TotalObjects))))
-		os.Stderr.WriteString(fmt.Sprintf(" tokens/lines matched: %s\n", humanize.Comma(int64(h.s.TotalValues))))
-		os.Stderr.WriteString(fmt.Sprintf("       histogram keys: %d\n", pairlist.Len()))
-		os.Stderr.WriteString(fmt.Sprintf("              runtime: %sms\n", humanize.Commaf(totalMillis)))
+		fmt.Fprintf(h.stats, "tokens/lines examined: %s\n", humanize.Comma(int64(st.Examined)))
+		fmt.Fprintf(h.stats, " tokens/lines matched: %s\n", humanize.Comma(int64(st.Matched)))
+		fmt.Fprintf(h.stats, "       histogram keys: %d\n", st.Keys)
+		if h.s.Agg == "distinct" {
+			accuracy := "exact"
+			if h.distinctError > 0 {
+				accuracy = fmt.Sprintf("±%.2f%% for counts estimated with HyperLogLog", h.distinctError*100)
+			}
+			fmt.Fprintf(h.stats, "       distinct error: %s\n", accuracy)
+		}
+		fmt.Fprintf(h.stats, "              runtime: %sms\n", humanize.Commaf(st.TotalMillis))
+		if h.summary != nil {
+			writeSummary(h.stats, h.summary)
+		}
+	}
+}
+
+// summaryStat is one named figure of the summary statistics
+type summaryStat struct {
+	Name  string
+	Value string
+}
+
+// summaryStats formats the summary statistics of numeric input for display,
+// rounded to two decimal places
+func summaryStats(s *stats.Summary) []summaryStat {
+	rows := []summaryStat{{"count", humanize.Comma(int64(s.Count))}}
+	for _, stat := range []struct {
+		name  string
+		value float64
+	}{
+		{"min", s.Min},
+		{"max", s.Max},
+		{"mean", s.Mean},
+		{"median", s.Median},
+		{"p90", s.P90},
+		{"p95", s.P95},
+		{"p99", s.P99},
+		{"stddev", s.StdDev},
+		{"mode", s.Mode},
+	} {
+		rows = append(rows, summaryStat{stat.name, humanize.Commaf(math.Floor(stat.value*100+0.5) / 100)})
+	}
+	return rows
+}
+
+// writeSummary writes the summary statistics of numeric input, lined up with
+// the rest of the verbose output
+func writeSummary(writer io.Writer, s *stats.Summary) {
+	for _, stat := range summaryStats(s) {
+		io.WriteString(writer, fmt.Sprintf("%21s: %s\n", stat.Name, stat.Value))
+	}
+}
+
+// additive reports whether the values can be added up, so that each is a
+// share of their total: not an average, extreme, percentile or last value
+func (h *Histogram) additive() bool {
+	switch h.s.Agg {
+	case "", "sum", "count", "distinct":
+		return true
 	}
+	return false
+}
 
-	histWidth := int(h.width) - (maxTokenLen + 1) - (maxValueWidth + 1) - (maxPctWidth + 1) - 1
+// valueHeader is the heading of the column of values: the aggregate given by
+// --agg, or "Ct" for counts
+func (h *Histogram) valueHeader() string {
+	if h.s.Agg != "" {
+		return h.s.Agg
+	}
+	return "Ct"
+}
+
+// WriteSnapshot renders the counts in snapshot as the --output setting asks,
+// or as a horizontal histogram by default, just as the command line tool
+// prints them
+func (h *Histogram) WriteSnapshot(writer io.Writer, snapshot counter.Snapshot) error {
+	switch h.s.Output {
+	case "svg":
+		return h.WriteSVG(writer, snapshot)
+	case "png":
+		return h.WritePNG(writer, snapshot)
+	case "md", "markdown":
+		return h.WriteMarkdown(writer, snapshot)
+	case "html":
+		return h.WriteHTML(writer, snapshot)
+	case "vertical", "vert", "v":
+		return h.WriteVertical(writer, snapshot)
+	case "spark", "sparkline":
+		h.WriteSparkline(writer, snapshot)
+	default:
+		h.WriteHist(writer, snapshot)
+	}
+	return nil
+}
 
-	os.Stderr.WriteString(Rjust("Key", maxTokenLen))
-	os.Stderr.WriteString("|")
-	os.Stderr.WriteString(Ljust("Ct", maxValueWidth))
-	os.Stderr.WriteString(" ")
-	os.Stderr.WriteString(Ljust("(Pct)", maxPctWidth))
-	os.Stderr.WriteString("  Histogram")
-	os.Stderr.WriteString(h.keyColor)
-	os.Stderr.WriteString("\n")
+func (h *Histogram) WriteHist(writer io.Writer, tokenCounts map[string]float64) {
+	l := h.newLayout(tokenCounts, int(h.height))
+	h.writeStats(h.runStats(l))
 
-	outputLimit := pairlist.Len()
-	if outputLimit > int(h.height) {
-		outputLimit = int(h.height)
+	io.WriteString(h.header, Rjust("Key", l.maxTokenLen))
+	io.WriteString(h.header, "|")
+	io.WriteString(h.header, Ljust(h.valueHeader(), l.maxValueWidth))
+	io.WriteString(h.header, " ")
+	if l.showPct {
+		io.WriteString(h.header, Ljust("(Pct)", l.maxPctWidth))
+		io.WriteString(h.header, " ")
 	}
-	for i, p := range (pairlist)[:outputLimit] {
-		io.WriteString(writer, Rjust(p.Key, maxTokenLen))
+	io.WriteString(h.header, " Histogram")
+	io.WriteString(h.header, h.keyColor)
+	io.WriteString(h.header, "\n")
+
+	histWidth := l.histWidth
+	if h.s.Binned && h.summary != nil {
+		histWidth -= binMarkerWidth
+	}
+
+	outputLimit := len(l.pairs)
+	for i, p := range l.pairs {
+		io.WriteString(writer, Rjust(p.Key, l.maxTokenLen))
 		io.WriteString(writer, h.regularColor)
 		io.WriteString(writer, "|")
 		io.WriteString(writer, h.ctColor)
 
-		outVal := fmt.Sprintf("%d", p.Value)
-		io.WriteString(writer, Rjust(outVal, maxValueWidth))
+		outVal := formatValue(p.Value)
+		io.WriteString(writer, Rjust(outVal, l.maxValueWidth))
 		io.WriteString(writer, " ")
 
-		pctStr := fmt.Sprintf("(%2.2f%%)", float64(p.Value)*1.0/float64(totalValue)*100.0)
-		io.WriteString(writer, h.pctColor)
-		io.WriteString(writer, Rjust(pctStr, maxPctWidth))
-		io.WriteString(writer, " ")
+		if l.showPct {
+			io.WriteString(writer, h.pctColor)
+			io.WriteString(writer, Rjust(l.pct(p.Value), l.maxPctWidth))
+			io.WriteString(writer, " ")
+		}
 
-		io.WriteString(writer, h.graphColor)
-		io.WriteString(writer, h.HistogramBar(histWidth, maxVal, p.Value))
+		if l.negative {
+			h.writeSignedBar(writer, histWidth, l.maxVal, p.Value)
+		} else {
+			io.WriteString(writer, h.graphColor)
+			io.WriteString(writer, h.HistogramBar(histWidth, l.maxVal, p.Value))
+		}
+		if marker := h.binMarker(p.Key); marker != "" {
+			io.WriteString(writer, h.regularColor)
+			io.WriteString(writer, marker)
+		}
 
 		if i == outputLimit-1 {
 			io.WriteString(writer, h.regularColor)
@@ -122,10 +352,66 @@ func (h *Histogram) WriteHist(writer io.Writer, tokenCounts map[string]uint) {
 	}
 }
 
-func (h *Histogram) HistogramBar(histWidth int, maxVal uint, barVal uint) string {
-	// given a value and max, return string for histogram bar of the proper
-	// number of characters, including unicode partial-width characters
+// writeSignedBar draws a bar either side of a zero axis in the middle of
+// histWidth: leftwards in the negative colour for a value below zero, and
+// rightwards in the graph colour otherwise
+func (h *Histogram) writeSignedBar(writer io.Writer, histWidth int, maxVal float64, barVal float64) {
+	halfWidth := (histWidth - 1) / 2
+	if halfWidth < 1 {
+		halfWidth = 1
+	}
+	// HistogramBar ends any non-empty bar with one more character, which has to fit too
+	bar := h.HistogramBar(halfWidth-1, maxVal, barVal)
+	if barVal < 0 {
+		io.WriteString(writer, h.negColor)
+		io.WriteString(writer, Rjust(reverse(bar), halfWidth))
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, "|")
+	} else {
+		io.WriteString(writer, strings.Repeat(" ", halfWidth))
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, "|")
+		io.WriteString(writer, h.graphColor)
+		io.WriteString(writer, bar)
+	}
+}
 
+// binMarkerWidth is the number of columns taken by the longest marker
+// binMarker returns
+const binMarkerWidth = len(" < mean, median")
+
+// binMarker labels the bin holding the mean or median of numeric input, or
+// returns "" for any other key
+func (h *Histogram) binMarker(key string) string {
+	if h.summary == nil {
+		return ""
+	}
+	lo, hi, closed, ok := stats.ParseBinKey(key)
+	if !ok {
+		return ""
+	}
+
+	var names []string
+	for _, stat := range []struct {
+		name  string
+		value float64
+	}{
+		{"mean", h.summary.Mean},
+		{"median", h.summary.Median},
+	} {
+		if stat.value >= lo && (stat.value < hi || closed && stat.value == hi) {
+			names = append(names, stat.name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return " ◀ " + strings.Join(names, ", ")
+}
+
+// barChars returns the character repeated along the length of a bar and the
+// character that finishes it off
+func (h *Histogram) barChars() (string, string) {
 	// first case is partial-width chars
 	var zeroChar, oneChar string
 	if h.s.CharWidth < 1.0 {
@@ -137,18 +423,25 @@ func (h *Histogram) HistogramBar(histWidth int, maxVal uint, barVal uint) string
 		zeroChar = h.s.HistogramChar
 		oneChar = h.s.HistogramChar
 	}
+	return zeroChar, oneChar
+}
 
-	// write out the full-width integer portion of the histogram
-	var intWidth int
-	var remainderWidth float32
-	if h.s.Logarithmic {
-		// TODO
-	} else {
-		width := float32(barVal) * 1.0 / float32(maxVal) * float32(histWidth)
-		intWidth = int(width)
-		remainderWidth = width - float32(intWidth)
+func (h *Histogram) HistogramBar(histWidth int, maxVal float64, barVal float64) string {
+	// given a value and max, return string for histogram bar of the proper
+	// number of characters, including unicode partial-width characters
+
+	// nothing was counted, or there is no room, so there is nothing to draw
+	if barVal == 0 || histWidth <= 0 {
+		return ""
 	}
 
+	zeroChar, oneChar := h.barChars()
+
+	// write out the full-width integer portion of the histogram
+	width := float32(h.barFraction(maxVal, barVal) * float64(histWidth))
+	intWidth := int(width)
+	remainderWidth := width - float32(intWidth)
+
 	// write the zeroeth character intWidth times...
 	bar := strings.Repeat(zeroChar, intWidth)
 
@@ -172,6 +465,51 @@ func (h *Histogram) HistogramBar(histWidth int, maxVal uint, barVal uint) string
 	return bar
 }
 
+// barFraction returns the proportion of the full histogram width that a bar
+// for barVal should fill, taking the logarithmic setting into account. Only
+// the size of barVal matters, not its sign.
+func (h *Histogram) barFraction(maxVal float64, barVal float64) float64 {
+	if maxVal == 0 {
+		return 0
+	}
+	if h.s.Logarithmic {
+		return math.Log1p(math.Abs(barVal)) / math.Log1p(maxVal)
+	}
+	return math.Abs(barVal) * 1.0 / maxVal
+}
+
+// unsigned returns an error naming a negative value in counts, for the
+// renderers that can only draw bars outwards from zero. what names the
+// renderer in the error.
+func unsigned(what string, counts map[string]float64) error {
+	negative := []string{}
+	for k, v := range counts {
+		if v < 0 {
+			negative = append(negative, k)
+		}
+	}
+	if len(negative) == 0 {
+		return nil
+	}
+	sort.Strings(negative)
+	return fmt.Errorf("%s cannot draw negative values, such as %s for %q", what, formatValue(counts[negative[0]]), negative[0])
+}
+
+// unsignedPairs is unsigned for the counts of each primary key
+func unsignedPairs(what string, pairCounts map[string]map[string]float64) error {
+	primaries := make([]string, 0, len(pairCounts))
+	for primary := range pairCounts {
+		primaries = append(primaries, primary)
+	}
+	sort.Strings(primaries)
+	for _, primary := range primaries {
+		if err := unsigned(what, pairCounts[primary]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func Ljust(s string, width int) string {
 	return fmt.Sprintf(fmt.Sprintf("%%-%ds", width), s)
 }
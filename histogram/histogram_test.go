@@ -3,10 +3,33 @@ package histogram
 import (
 	"bytes"
 	"testing"
+	"time"
 
+	"github.com/bradfordboyle/go-distribution/counter"
 	"github.com/bradfordboyle/go-distribution/settings"
+	"github.com/bradfordboyle/go-distribution/stats"
 )
 
+// newSettings returns the settings for args, failing the test if they are bad
+func newSettings(t *testing.T, args []string) *settings.Settings {
+	s, err := settings.NewSettings("testing", args)
+	if err != nil {
+		t.Fatalf("NewSettings returned an error: %s", err)
+	}
+	return s
+}
+
+// rowTotals returns the sum of each primary key's counts in pairCounts
+func rowTotals(pairCounts map[string]map[string]float64) map[string]float64 {
+	totals := make(map[string]float64)
+	for primary, row := range pairCounts {
+		for _, v := range row {
+			totals[primary] += v
+		}
+	}
+	return totals
+}
+
 func TestLjust(t *testing.T) {
 	s := Ljust("a", 4)
 	if s != "a   " {
@@ -25,18 +48,20 @@ func TestHistogram_HistogramBar(t *testing.T) {
 	testCases := []struct {
 		args      []string
 		histWidth int
-		maxVal    uint
-		barVal    uint
+		maxVal    float64
+		barVal    float64
 		expected  string
 	}{
 		{args: []string{"--char==>"}, histWidth: 10, maxVal: 10, barVal: 2, expected: "==>"},
 		{args: []string{"--char=dt"}, histWidth: 10, maxVal: 10, barVal: 2, expected: "•••"},
 		{args: []string{"--char=pb"}, histWidth: 10, maxVal: 100, barVal: 25, expected: "██▋"},
+		{args: []string{"--char==>"}, histWidth: 0, maxVal: 10, barVal: 2, expected: ""},
+		{args: []string{"--char==>"}, histWidth: -3, maxVal: 10, barVal: 2, expected: ""},
 	}
 
 	for _, tc := range testCases {
 		t.Run("something", func(t *testing.T) {
-			s := settings.NewSettings("testing", tc.args)
+			s := newSettings(t, tc.args)
 			h := NewHistogram(s)
 
 			bar := h.HistogramBar(tc.histWidth, tc.maxVal, tc.barVal)
@@ -58,26 +83,50 @@ func TestHistogram_WriteHist(t *testing.T) {
 	testCases := []struct {
 		name     string
 		args     []string
-		counts   map[string]uint
+		counts   map[string]float64
 		expected string
 	}{
 		{
 			name:     "Empty PairList",
 			args:     []string{RC_FILE, KV, WIDTH},
-			counts:   make(map[string]uint),
+			counts:   make(map[string]float64),
 			expected: "",
 		},
 		{
 			name:     "PairList w/ two tokens",
 			args:     []string{RC_FILE, KV, WIDTH},
-			counts:   map[string]uint{"a": 1, "b": 2},
+			counts:   map[string]float64{"a": 1, "b": 2},
 			expected: "b|2 (66.67%) --\na|1 (33.33%) -",
 		},
+		{
+			name:     "Bins in order, empty ones included",
+			args:     []string{RC_FILE, KV, "--width=30", "--bins=3"},
+			counts:   map[string]float64{"[7, 10]": 1, "[1, 4)": 2, "[4, 7)": 0},
+			expected: " [1, 4)|2 (66.67%) -----------\n [4, 7)|0  (0.00%) \n[7, 10]|1 (33.33%) ------",
+		},
+		{
+			name:     "Keys too long for any bar",
+			args:     []string{RC_FILE, KV, "--width=10"},
+			counts:   map[string]float64{"a-long-key": 2, "down": -1},
+			expected: "a-long-key| 2 (66.67%)  |\n      down|-1 (33.33%)  |",
+		},
+		{
+			name:     "No percentages of an average",
+			args:     []string{RC_FILE, KV, WIDTH, "--agg=mean"},
+			counts:   map[string]float64{"a": 1, "b": 2},
+			expected: "b|   2 --------\na|   1 ----",
+		},
+		{
+			name:     "Negative values left of the axis",
+			args:     []string{RC_FILE, KV, "--width=30"},
+			counts:   map[string]float64{"up": 2, "down": -1.5},
+			expected: "  up|   2 (57.14%)     |----\ndown|-1.5 (42.86%)  ---|",
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			s := settings.NewSettings("testing", tc.args)
+			s := newSettings(t, tc.args)
 			h := NewHistogram(s)
 			buf := new(bytes.Buffer)
 
@@ -89,3 +138,120 @@ func TestHistogram_WriteHist(t *testing.T) {
 		})
 	}
 }
+
+func TestHistogram_WriteSnapshot(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"Histogram", []string{RC_FILE, WIDTH}, "b|2 (66.67%) --\na|1 (33.33%) -"},
+		{"Sparkline", []string{RC_FILE, "--output=spark"}, "▅█\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := counter.New()
+			c.Add("a", 1)
+			c.Add("b", 2)
+			h := NewHistogram(newSettings(t, tc.args))
+			buf := new(bytes.Buffer)
+
+			if err := h.WriteSnapshot(buf, c.Snapshot()); err != nil {
+				t.Fatalf("WriteSnapshot returned an error: %s", err)
+			}
+			if buf.String() != tc.expected {
+				t.Errorf("WriteSnapshot incorrect: expected %s; actual %s", tc.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestHistogram_RejectsNegative(t *testing.T) {
+	counts := map[string]float64{"up": 2, "down": -1.5}
+	pairCounts := map[string]map[string]float64{"/a": counts}
+	totals := rowTotals(pairCounts)
+
+	testCases := []struct {
+		name     string
+		write    func(h *Histogram, buf *bytes.Buffer) error
+		expected string
+	}{
+		{"Vertical", func(h *Histogram, buf *bytes.Buffer) error { return h.WriteVertical(buf, counts) }, "a vertical histogram"},
+		{"Markdown", func(h *Histogram, buf *bytes.Buffer) error { return h.WriteMarkdown(buf, counts) }, "a Markdown histogram"},
+		{"HTML", func(h *Histogram, buf *bytes.Buffer) error { return h.WriteHTML(buf, counts) }, "an HTML histogram"},
+		{"SVG", func(h *Histogram, buf *bytes.Buffer) error { return h.WriteSVG(buf, counts) }, "an image histogram"},
+		{"PNG", func(h *Histogram, buf *bytes.Buffer) error { return h.WritePNG(buf, counts) }, "an image histogram"},
+		{"Grouped", func(h *Histogram, buf *bytes.Buffer) error {
+			return h.WriteGrouped(buf, pairCounts, []string{"up", "down"})
+		}, "a grouped histogram"},
+		{"Heatmap", func(h *Histogram, buf *bytes.Buffer) error { return h.WriteHeatmap(buf, pairCounts, totals, nil) }, "a heatmap"},
+		{"Stacked", func(h *Histogram, buf *bytes.Buffer) error { return h.WriteStacked(buf, pairCounts, totals) }, "a stacked histogram"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHistogram(newSettings(t, []string{RC_FILE}))
+			buf := new(bytes.Buffer)
+
+			expected := tc.expected + ` cannot draw negative values, such as -1.5 for "down"`
+			if err := tc.write(h, buf); err == nil || err.Error() != expected {
+				t.Errorf("error incorrect: expected %v; actual %v", expected, err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("output incorrect: expected nothing; actual %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestHistogram_SetWriters(t *testing.T) {
+	s := newSettings(t, []string{RC_FILE, WIDTH, "--verbose"})
+	s.TotalObjects, s.TotalValues = 4, 3
+	h := NewHistogram(s)
+	header, stats, body := new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)
+	h.SetWriters(header, stats)
+	h.SetRuntime(1500 * time.Millisecond)
+
+	h.WriteHist(body, map[string]float64{"a": 1, "b": 2})
+
+	testCases := []struct {
+		name     string
+		actual   string
+		expected string
+	}{
+		{"header", header.String(), "Key|Ct (Pct)     Histogram\n"},
+		{"stats", stats.String(), "tokens/lines examined: 4\n tokens/lines matched: 3\n       histogram keys: 2\n              runtime: 1,500ms\n"},
+		{"body", body.String(), "b|2 (66.67%) --\na|1 (33.33%) -"},
+	}
+	for _, tc := range testCases {
+		if tc.actual != tc.expected {
+			t.Errorf("%s incorrect: expected %q; actual %q", tc.name, tc.expected, tc.actual)
+		}
+	}
+}
+
+func TestHistogram_BinMarker(t *testing.T) {
+	s := newSettings(t, []string{RC_FILE, "--bins=3"})
+	h := NewHistogram(s)
+	h.SetSummary(&stats.Summary{Mean: 4.5, Median: 7})
+
+	testCases := []struct {
+		key      string
+		expected string
+	}{
+		{"[1, 4)", ""},
+		{"[4, 7)", " ◀ mean"},
+		{"[7, 10]", " ◀ median"},
+		{"[4, 10]", " ◀ mean, median"},
+		{"7", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			if marker := h.binMarker(tc.key); marker != tc.expected {
+				t.Errorf("binMarker incorrect: expected %q; actual %q", tc.expected, marker)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package histogram
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// the image renderers lay the histogram out on the same grid of character
+// cells as the terminal, so --width and --height mean the same thing for both
+const (
+	cellWidth  = 12
+	cellHeight = 20
+	glyphScale = 2
+)
+
+var (
+	backgroundColour = color.RGBA{0x1d, 0x1f, 0x21, 0xff}
+	foregroundColour = color.RGBA{0xc5, 0xc8, 0xc6, 0xff}
+)
+
+// ansiColours maps the SGR foreground codes used in palettes to the xterm
+// colours a terminal would show for them
+var ansiColours = map[int]color.RGBA{
+	30: {0x00, 0x00, 0x00, 0xff},
+	31: {0xcd, 0x00, 0x00, 0xff},
+	32: {0x00, 0xcd, 0x00, 0xff},
+	33: {0xcd, 0xcd, 0x00, 0xff},
+	34: {0x00, 0x00, 0xee, 0xff},
+	35: {0xcd, 0x00, 0xcd, 0xff},
+	36: {0x00, 0xcd, 0xcd, 0xff},
+	37: {0xe5, 0xe5, 0xe5, 0xff},
+	90: {0x7f, 0x7f, 0x7f, 0xff},
+	91: {0xff, 0x00, 0x00, 0xff},
+	92: {0x00, 0xff, 0x00, 0xff},
+	93: {0xff, 0xff, 0x00, 0xff},
+	94: {0x5c, 0x5c, 0xff, 0xff},
+	95: {0xff, 0x00, 0xff, 0xff},
+	96: {0x00, 0xff, 0xff, 0xff},
+	97: {0xff, 0xff, 0xff, 0xff},
+}
+
+// ansiColour converts a palette entry such as "32" or "1;34" to an RGB
+// colour, falling back to the default foreground for resets and attributes
+func ansiColour(code string) color.RGBA {
+	c := foregroundColour
+	for _, part := range strings.Split(code, ";") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if rgb, ok := ansiColours[n]; ok {
+			c = rgb
+		}
+	}
+	return c
+}
+
+// chartColours are the palette entries in the order --palette takes them
+type chartColours struct {
+	regular, key, ct, pct, graph color.RGBA
+}
+
+func (h *Histogram) chartColours() chartColours {
+	cl := strings.Split(h.s.ColourPalette, ",")
+	for len(cl) < 5 {
+		cl = append(cl, "0")
+	}
+	return chartColours{
+		regular: ansiColour(cl[0]),
+		key:     ansiColour(cl[1]),
+		ct:      ansiColour(cl[2]),
+		pct:     ansiColour(cl[3]),
+		graph:   ansiColour(cl[4]),
+	}
+}
+
+// label is a piece of text placed on the character grid; right-aligned
+// labels end at col, the rest start there
+type label struct {
+	col    int
+	row    int
+	text   string
+	colour color.RGBA
+	right  bool
+}
+
+// bar is a filled rectangle starting at col whose length is measured in
+// (possibly fractional) character cells
+type bar struct {
+	col    int
+	row    int
+	length float64
+	colour color.RGBA
+}
+
+// chart is a histogram laid out on the character grid, ready to be drawn by
+// one of the image renderers
+type chart struct {
+	cols   int
+	rows   int
+	labels []label
+	bars   []bar
+}
+
+func (h *Histogram) newChart(tokenCounts map[string]float64) (*chart, error) {
+	if err := unsigned("an image histogram", tokenCounts); err != nil {
+		return nil, err
+	}
+	l := h.newLayout(tokenCounts, int(h.height))
+	h.writeStats(h.runStats(l))
+
+	colours := h.chartColours()
+	ctCol := l.maxTokenLen + 1 + l.maxValueWidth
+	pctCol := ctCol
+	if l.showPct {
+		pctCol += 1 + l.maxPctWidth
+	}
+	barCol := pctCol + 1
+
+	histWidth := l.histWidth
+	if histWidth < 0 {
+		histWidth = 0
+	}
+
+	c := &chart{cols: int(h.width), rows: len(l.pairs) + 1}
+	c.labels = append(c.labels,
+		label{col: l.maxTokenLen, text: "Key", colour: colours.regular, right: true},
+		label{col: l.maxTokenLen, text: "|", colour: colours.regular},
+		label{col: l.maxTokenLen + 1, text: h.valueHeader(), colour: colours.regular},
+		label{col: barCol + 1, text: "Histogram", colour: colours.regular},
+	)
+	if l.showPct {
+		c.labels = append(c.labels, label{col: ctCol + 1, text: "(Pct)", colour: colours.regular})
+	}
+
+	for i, p := range l.pairs {
+		row := i + 1
+		c.labels = append(c.labels,
+			label{col: l.maxTokenLen, row: row, text: p.Key, colour: colours.key, right: true},
+			label{col: l.maxTokenLen, row: row, text: "|", colour: colours.regular},
+			label{col: ctCol, row: row, text: formatValue(p.Value), colour: colours.ct, right: true},
+		)
+		if l.showPct {
+			c.labels = append(c.labels, label{col: pctCol, row: row, text: l.pct(p.Value), colour: colours.pct, right: true})
+		}
+		c.bars = append(c.bars, bar{
+			col:    barCol,
+			row:    row,
+			length: h.barFraction(l.maxVal, p.Value) * float64(histWidth),
+			colour: colours.graph,
+		})
+	}
+
+	return c, nil
+}
+
+// size returns the dimensions of the rendered chart in pixels, including a
+// one-cell margin all the way round
+func (c *chart) size() (int, int) {
+	return (c.cols + 2) * cellWidth, (c.rows + 2) * cellHeight
+}
+
+// origin returns the pixel position of the top left of the given cell
+func (c *chart) origin(col int, row int) (int, int) {
+	return (col + 1) * cellWidth, (row + 1) * cellHeight
+}
+
+func svgColour(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// WriteSVG renders the histogram as a standalone SVG document
+func (h *Histogram) WriteSVG(writer io.Writer, tokenCounts map[string]float64) error {
+	c, err := h.newChart(tokenCounts)
+	if err != nil {
+		return err
+	}
+	width, height := c.size()
+
+	w := bufio.NewWriter(writer)
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", width, height, width, height)
+	fmt.Fprintf(w, "<rect width=\"100%%\" height=\"100%%\" fill=\"%s\"/>\n", svgColour(backgroundColour))
+	fmt.Fprintf(w, "<g font-family=\"monospace\" font-size=\"%d\">\n", cellHeight*3/4)
+
+	for _, lb := range c.labels {
+		x, y := c.origin(lb.col, lb.row)
+		anchor := "start"
+		if lb.right {
+			anchor = "end"
+		}
+		fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" fill=\"%s\" text-anchor=\"%s\" xml:space=\"preserve\">", x, y+cellHeight*3/4, svgColour(lb.colour), anchor)
+		xml.EscapeText(w, []byte(lb.text))
+		fmt.Fprintf(w, "</text>\n")
+	}
+
+	for _, b := range c.bars {
+		x, y := c.origin(b.col, b.row)
+		fmt.Fprintf(w, "<rect x=\"%d\" y=\"%d\" width=\"%.2f\" height=\"%d\" fill=\"%s\"/>\n", x, y+cellHeight/5, b.length*cellWidth, cellHeight*3/5, svgColour(b.colour))
+	}
+
+	fmt.Fprintf(w, "</g>\n")
+	fmt.Fprintf(w, "</svg>\n")
+
+	return w.Flush()
+}
+
+// WritePNG renders the histogram as a PNG image
+func (h *Histogram) WritePNG(writer io.Writer, tokenCounts map[string]float64) error {
+	c, err := h.newChart(tokenCounts)
+	if err != nil {
+		return err
+	}
+	width, height := c.size()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(backgroundColour), image.Point{}, draw.Src)
+
+	for _, b := range c.bars {
+		x, y := c.origin(b.col, b.row)
+		r := image.Rect(x, y+cellHeight/5, x+int(b.length*cellWidth+0.5), y+cellHeight*4/5)
+		draw.Draw(img, r, image.NewUniform(b.colour), image.Point{}, draw.Src)
+	}
+
+	for _, lb := range c.labels {
+		x, y := c.origin(lb.col, lb.row)
+		if lb.right {
+			x -= utf8.RuneCountInString(lb.text) * cellWidth
+		}
+		drawText(img, x, y, lb.text, lb.colour)
+	}
+
+	return png.Encode(writer, img)
+}
+
+// drawText writes s into img one glyph per character cell, with the top left
+// of the first cell at (x, y)
+func drawText(img *image.RGBA, x int, y int, s string, c color.RGBA) {
+	top := y + (cellHeight-7*glyphScale)/2
+	for _, r := range s {
+		left := x + (cellWidth-5*glyphScale)/2
+		for col, bits := range glyph(r) {
+			for row := 0; row < 7; row++ {
+				if bits&(1<<uint(row)) == 0 {
+					continue
+				}
+				px := image.Rect(0, 0, glyphScale, glyphScale).Add(image.Pt(left+col*glyphScale, top+row*glyphScale))
+				draw.Draw(img, px, image.NewUniform(c), image.Point{}, draw.Src)
+			}
+		}
+		x += cellWidth
+	}
+}
@@ -0,0 +1,71 @@
+package histogram
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestAnsiColour(t *testing.T) {
+	testCases := []struct {
+		code     string
+		expected color.RGBA
+	}{
+		{"0", foregroundColour},
+		{"32", ansiColours[32]},
+		{"1;34", ansiColours[34]},
+		{"97", ansiColours[97]},
+		{"bogus", foregroundColour},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.code, func(t *testing.T) {
+			c := ansiColour(tc.code)
+			if c != tc.expected {
+				t.Errorf("ansiColour incorrect: expected %v; actual %v", tc.expected, c)
+			}
+		})
+	}
+}
+
+func TestHistogram_WriteSVG(t *testing.T) {
+	s := newSettings(t, []string{RC_FILE, WIDTH, "--palette=0,0,32,35,31"})
+	h := NewHistogram(s)
+	buf := new(bytes.Buffer)
+
+	if err := h.WriteSVG(buf, map[string]float64{"a<b": 1, "c": 2}); err != nil {
+		t.Fatalf("WriteSVG returned an error: %s", err)
+	}
+
+	svg := buf.String()
+	for _, expected := range []string{"<svg ", ">a&lt;b</text>", ">c</text>", ">(66.67%)</text>", `fill="#cd0000"`, "</svg>"} {
+		if !strings.Contains(svg, expected) {
+			t.Errorf("WriteSVG incorrect: expected output to contain %s", expected)
+		}
+	}
+	if strings.Count(svg, "<rect ") != 3 {
+		t.Errorf("WriteSVG incorrect: expected %d rects; actual %d", 3, strings.Count(svg, "<rect "))
+	}
+}
+
+func TestHistogram_WritePNG(t *testing.T) {
+	s := newSettings(t, []string{RC_FILE, WIDTH})
+	h := NewHistogram(s)
+	buf := new(bytes.Buffer)
+
+	if err := h.WritePNG(buf, map[string]float64{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("WritePNG returned an error: %s", err)
+	}
+
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatalf("WritePNG did not produce a valid PNG: %s", err)
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() != (15+2)*cellWidth || bounds.Dy() != (3+2)*cellHeight {
+		t.Errorf("WritePNG size incorrect: expected %dx%d; actual %dx%d", (15+2)*cellWidth, (3+2)*cellHeight, bounds.Dx(), bounds.Dy())
+	}
+}
@@ -1,8 +1,15 @@
 package histogram
 
+import (
+	"math"
+	"strconv"
+
+	"github.com/bradfordboyle/go-distribution/stats"
+)
+
 type pair struct {
 	Key   string
-	Value uint
+	Value float64
 }
 
 type pairlist []pair
@@ -20,8 +27,47 @@ func (pl pairlist) Swap(i, j int) {
 	pl[i], pl[j] = pl[j], pl[i]
 }
 
+// byMagnitude orders a pairlist by the size of each value, ignoring its sign
+type byMagnitude struct{ pairlist }
+
+func (b byMagnitude) Less(i, j int) bool {
+	x, y := math.Abs(b.pairlist[i].Value), math.Abs(b.pairlist[j].Value)
+	if x == y {
+		return b.pairlist[i].Key < b.pairlist[j].Key
+	}
+	return x < y
+}
+
+// byKey orders a pairlist by key rather than by value
+type byKey struct{ pairlist }
+
+func (b byKey) Less(i, j int) bool {
+	return keyLess(b.pairlist[i].Key, b.pairlist[j].Key)
+}
+
+// keyLess compares keys numerically when both are numbers (or bins of
+// numbers), so that "9" sorts before "10", and lexically otherwise
+func keyLess(a, b string) bool {
+	x, okA := keyNumber(a)
+	y, okB := keyNumber(b)
+	if okA && okB && x != y {
+		return x < y
+	}
+	return a < b
+}
+
+// keyNumber returns the number a key stands for: the key itself, or the lower
+// edge of a bin
+func keyNumber(key string) (float64, bool) {
+	if lo, _, _, ok := stats.ParseBinKey(key); ok {
+		return lo, true
+	}
+	f, err := strconv.ParseFloat(key, 64)
+	return f, err == nil
+}
+
 // NewPairList returns a pairlist containing pairs (key, value) from the give map
-func NewPairList(m map[string]uint) pairlist {
+func NewPairList(m map[string]float64) pairlist {
 	p := make(pairlist, len(m))
 
 	i := 0
@@ -33,11 +79,12 @@ func NewPairList(m map[string]uint) pairlist {
 	return p
 }
 
-// TotalValues returns the sum of values across all pairs in the PairList
-func (pl *pairlist) TotalValues() uint {
-	totalValue := uint(0)
+// TotalValues returns the sum of the sizes of values across all pairs in the
+// PairList, so that negative values count towards it as much as positive ones
+func (pl *pairlist) TotalValues() float64 {
+	totalValue := 0.0
 	for _, p := range *pl {
-		totalValue += p.Value
+		totalValue += math.Abs(p.Value)
 	}
 
 	return totalValue
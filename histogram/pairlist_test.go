@@ -5,7 +5,7 @@ import (
 )
 
 func TestNewPairList(t *testing.T) {
-	m := map[string]uint{
+	m := map[string]float64{
 		"rsc": 3711,
 		"r":   2138,
 		"gri": 1908,
@@ -18,7 +18,7 @@ func TestNewPairList(t *testing.T) {
 			t.Errorf("Original map did not contaim %s", p.Key)
 		}
 		if i != p.Value {
-			t.Errorf("PairList had the wrong value for %s; expected %d, actual %d", p.Key, i, p.Value)
+			t.Errorf("PairList had the wrong value for %s; expected %v, actual %v", p.Key, i, p.Value)
 		}
 	}
 }
@@ -80,6 +80,32 @@ func TestPairlist_TotalValues(t *testing.T) {
 	})
 
 	if pl.TotalValues() != 4 {
-		t.Errorf("PairList.TotalValue() returned incorrect result; expected %d, actual %d", 4, pl.TotalValues())
+		t.Errorf("PairList.TotalValue() returned incorrect result; expected %v, actual %v", 4, pl.TotalValues())
+	}
+	pl = append(pl, pair{Key: "c", Value: -3})
+	if pl.TotalValues() != 7 {
+		t.Errorf("PairList.TotalValue() with a negative value returned incorrect result; expected %v, actual %v", 7, pl.TotalValues())
+	}
+}
+
+func TestKeyLess(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"9", "10", true},
+		{"10", "9", false},
+		{"a", "b", true},
+		{"10", "a", true},
+		{"1.5", "1.25", false},
+		{"[9, 10)", "[10, 20]", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.a+" < "+tc.b, func(t *testing.T) {
+			if keyLess(tc.a, tc.b) != tc.expected {
+				t.Errorf("keyLess(%s, %s) incorrect: expected %t", tc.a, tc.b, tc.expected)
+			}
+		})
 	}
 }
@@ -0,0 +1,216 @@
+package histogram
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// blockBar returns a bar width cells long drawn with the partial block
+// characters, so fractions of a cell down to an eighth are visible
+func (h *Histogram) blockBar(width float64) string {
+	if width <= 0 {
+		return ""
+	}
+	blocks := h.s.PartialBlocks
+	eighths := int(math.Floor(width * float64(len(blocks))))
+	bar := strings.Repeat(blocks[len(blocks)-1], eighths/len(blocks))
+	if rem := eighths % len(blocks); rem > 0 {
+		bar += blocks[rem-1]
+	}
+	return bar
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"|", `\|`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+)
+
+// WriteMarkdown renders the histogram as a GitHub-flavoured Markdown table,
+// followed by a table of the summary statistics if the input was numeric.
+// Values below zero are rejected.
+func (h *Histogram) WriteMarkdown(writer io.Writer, tokenCounts map[string]float64) error {
+	if err := unsigned("a Markdown histogram", tokenCounts); err != nil {
+		return err
+	}
+	l := h.newLayout(tokenCounts, int(h.height))
+	h.writeStats(h.runStats(l))
+
+	histWidth := l.histWidth
+	if histWidth < 1 {
+		histWidth = 1
+	}
+
+	header := fmt.Sprintf("| Key | %s | Pct | Histogram |\n| --: | --: | --: | :-- |\n", h.valueHeader())
+	if !l.showPct {
+		header = fmt.Sprintf("| Key | %s | Histogram |\n| --: | --: | :-- |\n", h.valueHeader())
+	}
+	if _, err := io.WriteString(writer, header); err != nil {
+		return err
+	}
+	for _, p := range l.pairs {
+		pct := ""
+		if l.showPct {
+			pct = fmt.Sprintf(" %2.2f%% |", l.percent(p.Value))
+		}
+		_, err := fmt.Fprintf(writer, "| %s | %s |%s %s |\n",
+			markdownEscaper.Replace(p.Key),
+			formatValue(p.Value),
+			pct,
+			h.blockBar(h.barFraction(l.maxVal, p.Value)*float64(histWidth)))
+		if err != nil {
+			return err
+		}
+	}
+
+	if h.summary != nil {
+		if _, err := io.WriteString(writer, "\n| Statistic | Value |\n| :-- | --: |\n"); err != nil {
+			return err
+		}
+		for _, stat := range summaryStats(h.summary) {
+			if _, err := fmt.Fprintf(writer, "| %s | %s |\n", stat.Name, stat.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type htmlRow struct {
+	Key     string
+	Value   float64
+	Percent float64
+	Width   float64
+}
+
+type htmlColours struct {
+	Background, Regular, Key, Ct, Pct, Graph template.CSS
+}
+
+type htmlReport struct {
+	Colours htmlColours
+	Header  string
+	ShowPct bool
+	Rows    []htmlRow
+	Stats   runStats
+	Shown   int
+	Summary []summaryStat
+}
+
+var htmlFuncs = template.FuncMap{
+	"comma":  func(n uint64) string { return humanize.Comma(int64(n)) },
+	"commaf": humanize.Commaf,
+	"pct":    func(f float64) string { return fmt.Sprintf("%2.2f%%", f) },
+	"uint64": func(n uint) uint64 { return uint64(n) },
+	"value":  formatValue,
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(htmlFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Histogram</title>
+<style>
+body { background: {{.Colours.Background}}; color: {{.Colours.Regular}}; font-family: monospace; }
+table { border-collapse: collapse; }
+th { cursor: pointer; text-align: left; padding: 0.2em 0.6em; }
+td { padding: 0.2em 0.6em; }
+td.key { color: {{.Colours.Key}}; text-align: right; }
+td.ct { color: {{.Colours.Ct}}; text-align: right; }
+td.pct { color: {{.Colours.Pct}}; text-align: right; }
+td.bar { width: 60ex; }
+td.bar div { background: {{.Colours.Graph}}; height: 1em; }
+dl.stats { display: grid; grid-template-columns: max-content auto; gap: 0 1em; }
+dl.stats dd { margin: 0; }
+</style>
+</head>
+<body>
+<table id="histogram">
+<thead>
+<tr><th data-type="string">Key</th><th data-type="number">{{.Header}}</th>{{if .ShowPct}}<th data-type="number">Pct</th>{{end}}<th data-type="number">Histogram</th></tr>
+</thead>
+<tbody>
+{{- range .Rows}}
+<tr><td class="key" data-value="{{.Key}}">{{.Key}}</td><td class="ct" data-value="{{.Value}}">{{value .Value}}</td>{{if $.ShowPct}}<td class="pct" data-value="{{.Percent}}">{{pct .Percent}}</td>{{end}}<td class="bar" data-value="{{.Value}}"><div style="width: {{printf "%.2f" .Width}}%"></div></td></tr>
+{{- end}}
+</tbody>
+</table>
+<dl class="stats">
+<dt>tokens/lines examined</dt><dd>{{comma (uint64 .Stats.Examined)}}</dd>
+<dt>tokens/lines matched</dt><dd>{{comma .Stats.Matched}}</dd>
+<dt>histogram keys</dt><dd>{{.Stats.Keys}} ({{.Shown}} shown)</dd>
+<dt>runtime</dt><dd>{{commaf .Stats.TotalMillis}}ms</dd>
+{{- range .Summary}}
+<dt>{{.Name}}</dt><dd>{{.Value}}</dd>
+{{- end}}
+</dl>
+<script>
+document.querySelectorAll("#histogram th").forEach(function (th, col) {
+  var descending = false;
+  th.addEventListener("click", function () {
+    var tbody = document.querySelector("#histogram tbody");
+    var numeric = th.dataset.type === "number";
+    descending = !descending;
+    Array.prototype.slice.call(tbody.rows).sort(function (a, b) {
+      var x = a.cells[col].dataset.value, y = b.cells[col].dataset.value;
+      var c = numeric ? x - y : x.localeCompare(y);
+      return descending ? -c : c;
+    }).forEach(function (row) { tbody.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+// WriteHTML renders the histogram as a self-contained HTML page with
+// sortable columns, the run statistics and the summary statistics if the
+// input was numeric. Values below zero are rejected.
+func (h *Histogram) WriteHTML(writer io.Writer, tokenCounts map[string]float64) error {
+	if err := unsigned("an HTML histogram", tokenCounts); err != nil {
+		return err
+	}
+	l := h.newLayout(tokenCounts, int(h.height))
+	st := h.runStats(l)
+	h.writeStats(st)
+
+	colours := h.chartColours()
+	report := htmlReport{
+		Colours: htmlColours{
+			Background: template.CSS(svgColour(backgroundColour)),
+			Regular:    template.CSS(svgColour(colours.regular)),
+			Key:        template.CSS(svgColour(colours.key)),
+			Ct:         template.CSS(svgColour(colours.ct)),
+			Pct:        template.CSS(svgColour(colours.pct)),
+			Graph:      template.CSS(svgColour(colours.graph)),
+		},
+		Header:  h.valueHeader(),
+		ShowPct: l.showPct,
+		Stats:   st,
+		Shown:   len(l.pairs),
+	}
+	if h.summary != nil {
+		report.Summary = summaryStats(h.summary)
+	}
+	for _, p := range l.pairs {
+		report.Rows = append(report.Rows, htmlRow{
+			Key:     p.Key,
+			Value:   p.Value,
+			Percent: l.percent(p.Value),
+			Width:   h.barFraction(l.maxVal, p.Value) * 100,
+		})
+	}
+
+	return htmlTemplate.Execute(writer, report)
+}
@@ -0,0 +1,104 @@
+package histogram
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bradfordboyle/go-distribution/stats"
+)
+
+func TestHistogram_BlockBar(t *testing.T) {
+	testCases := []struct {
+		width    float64
+		expected string
+	}{
+		{0, ""},
+		{0.125, "▏"},
+		{1, "█"},
+		{2.5, "██▌"},
+	}
+
+	s := newSettings(t, []string{RC_FILE})
+	h := NewHistogram(s)
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			bar := h.blockBar(tc.width)
+			if bar != tc.expected {
+				t.Errorf("blockBar incorrect: expected %s; actual %s", tc.expected, bar)
+			}
+		})
+	}
+}
+
+func TestHistogram_WriteMarkdown(t *testing.T) {
+	s := newSettings(t, []string{RC_FILE, WIDTH})
+	h := NewHistogram(s)
+	buf := new(bytes.Buffer)
+
+	if err := h.WriteMarkdown(buf, map[string]float64{"a|b": 1, "c": 2}); err != nil {
+		t.Fatalf("WriteMarkdown returned an error: %s", err)
+	}
+
+	expected := "| Key | Ct | Pct | Histogram |\n" +
+		"| --: | --: | --: | :-- |\n" +
+		"| c | 2 | 66.67% | █ |\n" +
+		"| a\\|b | 1 | 33.33% | ▌ |\n"
+	if buf.String() != expected {
+		t.Errorf("WriteMarkdown incorrect: expected %s; actual %s", expected, buf.String())
+	}
+}
+
+func TestHistogram_WriteMarkdownAggregate(t *testing.T) {
+	s := newSettings(t, []string{RC_FILE, WIDTH, "--agg=p95"})
+	h := NewHistogram(s)
+	buf := new(bytes.Buffer)
+
+	if err := h.WriteMarkdown(buf, map[string]float64{"/a": 0.25}); err != nil {
+		t.Fatalf("WriteMarkdown returned an error: %s", err)
+	}
+
+	expected := "| Key | p95 | Histogram |\n| --: | --: | :-- |\n| /a | 0.25 |"
+	if !strings.HasPrefix(buf.String(), expected) {
+		t.Errorf("WriteMarkdown header incorrect: expected %s; actual %s", expected, buf.String())
+	}
+}
+
+func TestHistogram_WriteMarkdownSummary(t *testing.T) {
+	s := newSettings(t, []string{RC_FILE, WIDTH})
+	h := NewHistogram(s)
+	h.SetSummary(stats.Summarize(stats.Sample{1: 1, 2: 2}))
+	buf := new(bytes.Buffer)
+
+	if err := h.WriteMarkdown(buf, map[string]float64{"1": 1, "2": 2}); err != nil {
+		t.Fatalf("WriteMarkdown returned an error: %s", err)
+	}
+
+	expected := "| Statistic | Value |\n" +
+		"| :-- | --: |\n" +
+		"| count | 3 |\n" +
+		"| min | 1 |\n" +
+		"| max | 2 |\n" +
+		"| mean | 1.67 |\n" +
+		"| median | 2 |\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("WriteMarkdown summary incorrect: expected %s; actual %s", expected, buf.String())
+	}
+}
+
+func TestHistogram_WriteHTML(t *testing.T) {
+	s := newSettings(t, []string{RC_FILE, WIDTH})
+	h := NewHistogram(s)
+	buf := new(bytes.Buffer)
+
+	if err := h.WriteHTML(buf, map[string]float64{"<a>": 1, "c": 2}); err != nil {
+		t.Fatalf("WriteHTML returned an error: %s", err)
+	}
+
+	html := buf.String()
+	for _, expected := range []string{"&lt;a&gt;", "66.67%", "width: 50.00%", "histogram keys", "<script>"} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("WriteHTML incorrect: expected output to contain %s", expected)
+		}
+	}
+}
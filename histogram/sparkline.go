@@ -0,0 +1,52 @@
+package histogram
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// WriteSparkline renders the histogram on a single line, one character per
+// key, in key order (unless --sort=count). If any value is negative the line
+// runs from the lowest value to the highest rather than from zero. With
+// --sparkstats the minimum, maximum and last values follow the line.
+func (h *Histogram) WriteSparkline(writer io.Writer, tokenCounts map[string]float64) {
+	l := h.newLayout(tokenCounts, int(h.width))
+	if h.s.Sort != "count" {
+		sort.Stable(byKey{l.pairs})
+	}
+	h.writeStats(h.runStats(l))
+
+	if len(l.pairs) == 0 {
+		return
+	}
+
+	minVal, maxVal := l.pairs[0].Value, l.pairs[0].Value
+	for _, p := range l.pairs {
+		minVal = math.Min(minVal, p.Value)
+		maxVal = math.Max(maxVal, p.Value)
+	}
+
+	blocks := h.s.PartialColumns
+	io.WriteString(writer, h.graphColor)
+	for _, p := range l.pairs {
+		// values below zero scale the line from the lowest value instead
+		fraction := h.barFraction(l.maxVal, p.Value)
+		if minVal < 0 && maxVal > minVal {
+			fraction = (p.Value - minVal) / (maxVal - minVal)
+		}
+		level := int(math.Floor(fraction*float64(len(blocks)-1) + 0.5))
+		io.WriteString(writer, blocks[level])
+	}
+	io.WriteString(writer, h.regularColor)
+
+	if h.s.SparkStats {
+		last := l.pairs[len(l.pairs)-1]
+		io.WriteString(writer, fmt.Sprintf(" min %s%s%s max %s%s%s last %s%s%s",
+			h.ctColor, formatValue(minVal), h.regularColor,
+			h.ctColor, formatValue(maxVal), h.regularColor,
+			h.ctColor, formatValue(last.Value), h.regularColor))
+	}
+	io.WriteString(writer, "\n")
+}
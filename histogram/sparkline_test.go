@@ -0,0 +1,60 @@
+package histogram
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHistogram_WriteSparkline(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		counts   map[string]float64
+		expected string
+	}{
+		{
+			name:     "Empty PairList",
+			args:     []string{RC_FILE},
+			counts:   make(map[string]float64),
+			expected: "",
+		},
+		{
+			name:     "Key order",
+			args:     []string{RC_FILE},
+			counts:   map[string]float64{"1": 0, "2": 7, "10": 14, "3": 3},
+			expected: "▁▅▃█\n",
+		},
+		{
+			name:     "Count order",
+			args:     []string{RC_FILE, "--sort=count"},
+			counts:   map[string]float64{"1": 0, "2": 7, "10": 14, "3": 3},
+			expected: "█▅▃▁\n",
+		},
+		{
+			name:     "Limited to width",
+			args:     []string{RC_FILE, "--width=2"},
+			counts:   map[string]float64{"1": 0, "2": 7, "10": 14, "3": 3},
+			expected: "▅█\n",
+		},
+		{
+			name:     "With stats",
+			args:     []string{RC_FILE, "--sparkstats"},
+			counts:   map[string]float64{"a": 2, "b": 1, "c": 4},
+			expected: "▅▃█ min 1 max 4 last 4\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSettings(t, tc.args)
+			h := NewHistogram(s)
+			buf := new(bytes.Buffer)
+
+			h.WriteSparkline(buf, tc.counts)
+
+			if buf.String() != tc.expected {
+				t.Errorf("WriteSparkline incorrect: expected %s; actual %s", tc.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package histogram
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// segmentChars tell the segments of a stacked bar apart when the output is
+// not colourised
+var segmentChars = []string{"█", "▓", "▒", "░", "#", "=", "+", "*"}
+
+// seriesStyle returns the colour and the character used to draw the i'th
+// series of a stacked or grouped bar
+func (h *Histogram) seriesStyle(i int) (string, string) {
+	if len(h.s.SeriesColours) > 0 {
+		zeroChar, _ := h.barChars()
+		return h.s.SeriesColours[i%len(h.s.SeriesColours)], zeroChar
+	}
+	return "", segmentChars[i%len(segmentChars)]
+}
+
+// writeLegend writes the character and colour used for each series
+func (h *Histogram) writeLegend(writer io.Writer, series []string) {
+	for i, name := range series {
+		colour, char := h.seriesStyle(i)
+		io.WriteString(writer, "  ")
+		io.WriteString(writer, colour)
+		io.WriteString(writer, strings.Repeat(char, 2))
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, " ")
+		io.WriteString(writer, name)
+	}
+	io.WriteString(writer, "\n")
+}
+
+// WriteStacked renders a bar for each of the highest-counted primary keys,
+// split into a segment for each of its secondary keys. pairCounts maps
+// primary key to secondary key to count, and totals each primary key to the
+// sum of its counts. Counts below zero are rejected.
+func (h *Histogram) WriteStacked(writer io.Writer, pairCounts map[string]map[string]float64, totals map[string]float64) error {
+	if err := unsignedPairs("a stacked histogram", pairCounts); err != nil {
+		return err
+	}
+
+	l := h.newLayout(totals, int(h.height))
+	h.writeStats(h.runStats(l))
+
+	seriesSet := make(map[string]float64)
+	for _, p := range l.pairs {
+		for secondary := range pairCounts[p.Key] {
+			seriesSet[secondary] = 0
+		}
+	}
+	series := NewPairList(seriesSet)
+	sort.Sort(byKey{series})
+	names := make([]string, len(series))
+	for i, s := range series {
+		names[i] = s.Key
+	}
+
+	io.WriteString(h.header, Rjust("Key", l.maxTokenLen))
+	io.WriteString(h.header, "|")
+	io.WriteString(h.header, Ljust("Ct", l.maxValueWidth))
+	io.WriteString(h.header, " ")
+	io.WriteString(h.header, Ljust("(Pct)", l.maxPctWidth))
+	io.WriteString(h.header, " ")
+	h.writeLegend(h.header, names)
+
+	for _, p := range l.pairs {
+		io.WriteString(writer, h.keyColor)
+		io.WriteString(writer, Rjust(p.Key, l.maxTokenLen))
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, "|")
+		io.WriteString(writer, h.ctColor)
+		io.WriteString(writer, Rjust(formatValue(p.Value), l.maxValueWidth))
+		io.WriteString(writer, " ")
+		io.WriteString(writer, h.pctColor)
+		io.WriteString(writer, Rjust(l.pct(p.Value), l.maxPctWidth))
+		io.WriteString(writer, " ")
+
+		// place each segment boundary by the running total so that rounding
+		// never makes the whole bar longer or shorter than it should be
+		length := h.barFraction(l.maxVal, p.Value) * float64(l.histWidth)
+		running, drawn := 0.0, 0
+		for i, name := range names {
+			running += pairCounts[p.Key][name]
+			end := int(math.Floor(running/p.Value*length + 0.5))
+			if end > drawn {
+				colour, char := h.seriesStyle(i)
+				io.WriteString(writer, colour)
+				io.WriteString(writer, strings.Repeat(char, end-drawn))
+				drawn = end
+			}
+		}
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, "\n")
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package histogram
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHistogram_SeriesStyle(t *testing.T) {
+	s := newSettings(t, []string{RC_FILE, "--char=ba", "--seriespalette=31,32"})
+	h := NewHistogram(s)
+
+	for i, expected := range []string{"\u001b[31m", "\u001b[32m", "\u001b[31m"} {
+		colour, char := h.seriesStyle(i)
+		if colour != expected || char != "▬" {
+			t.Errorf("seriesStyle(%d) incorrect: expected %q %s; actual %q %s", i, expected, "▬", colour, char)
+		}
+	}
+}
+
+func TestHistogram_WriteStacked(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		counts   map[string]map[string]float64
+		expected string
+	}{
+		{
+			name:     "Empty PairCounts",
+			args:     []string{RC_FILE, "--width=30"},
+			counts:   make(map[string]map[string]float64),
+			expected: "",
+		},
+		{
+			name: "Two rows",
+			args: []string{RC_FILE, "--width=30"},
+			counts: map[string]map[string]float64{
+				"/a": {"2xx": 6, "5xx": 2},
+				"/b": {"2xx": 1, "4xx": 1, "5xx": 2},
+			},
+			expected: "/a|8 (66.67%) ███████████▒▒▒▒\n" +
+				"/b|4 (33.33%) ██▓▓▒▒▒▒\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSettings(t, tc.args)
+			h := NewHistogram(s)
+			buf := new(bytes.Buffer)
+
+			if err := h.WriteStacked(buf, tc.counts, rowTotals(tc.counts)); err != nil {
+				t.Fatalf("WriteStacked returned an error: %s", err)
+			}
+
+			if buf.String() != tc.expected {
+				t.Errorf("WriteStacked incorrect: expected\n%s\nactual\n%s", tc.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package histogram
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxColumnWidth caps how wide each column of a vertical histogram may grow
+// when there are only a few keys to share the width between
+const maxColumnWidth = 4
+
+// WriteVertical renders the histogram as a column chart, with keys along the
+// x-axis in key order (unless --sort=count) and a scale up the y-axis. Values
+// below zero are rejected.
+func (h *Histogram) WriteVertical(writer io.Writer, tokenCounts map[string]float64) error {
+	if err := unsigned("a vertical histogram", tokenCounts); err != nil {
+		return err
+	}
+
+	maxVal := 0.0
+	for _, v := range tokenCounts {
+		if math.Abs(v) > maxVal {
+			maxVal = math.Abs(v)
+		}
+	}
+
+	height := int(h.height)
+	axisWidth := len(formatValue(maxVal))
+	plotWidth := int(h.width) - axisWidth - 1
+	if plotWidth < 1 {
+		plotWidth = 1
+	}
+
+	l := h.newLayout(tokenCounts, plotWidth)
+	if h.s.Sort != "count" {
+		sort.Stable(byKey{l.pairs})
+	}
+	h.writeStats(h.runStats(l))
+
+	if len(l.pairs) == 0 {
+		return nil
+	}
+
+	columnWidth := plotWidth / len(l.pairs)
+	if columnWidth > maxColumnWidth {
+		columnWidth = maxColumnWidth
+	}
+	barWidth := columnWidth
+	if columnWidth > 1 {
+		barWidth--
+	}
+	gap := strings.Repeat(" ", columnWidth-barWidth)
+
+	// each column's height in eighths of a row
+	eighths := make([]int, len(l.pairs))
+	for i, p := range l.pairs {
+		eighths[i] = int(math.Floor(h.barFraction(l.maxVal, p.Value)*float64(height*8) + 0.5))
+	}
+
+	blocks := h.s.PartialColumns
+	for row := 0; row < height; row++ {
+		floor := (height - 1 - row) * 8
+
+		label, tick := "", "│"
+		if row == 0 || row == height/2 {
+			label, tick = h.axisValue(l.maxVal, float64(height-row)/float64(height)), "┤"
+		}
+		io.WriteString(writer, h.ctColor)
+		io.WriteString(writer, Rjust(label, axisWidth))
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, tick)
+		io.WriteString(writer, h.graphColor)
+
+		for _, e := range eighths {
+			cell := " "
+			if e >= floor+len(blocks) {
+				cell = blocks[len(blocks)-1]
+			} else if e > floor {
+				cell = blocks[e-floor-1]
+			}
+			io.WriteString(writer, strings.Repeat(cell, barWidth))
+			io.WriteString(writer, gap)
+		}
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, "\n")
+	}
+
+	io.WriteString(writer, h.ctColor)
+	io.WriteString(writer, Rjust("0", axisWidth))
+	io.WriteString(writer, h.regularColor)
+	io.WriteString(writer, "└")
+	io.WriteString(writer, strings.Repeat("─", columnWidth*len(l.pairs)))
+	io.WriteString(writer, "\n")
+
+	for _, line := range h.axisLabels(l.pairs, columnWidth, height) {
+		io.WriteString(writer, strings.Repeat(" ", axisWidth+1))
+		io.WriteString(writer, h.keyColor)
+		io.WriteString(writer, line)
+		io.WriteString(writer, h.regularColor)
+		io.WriteString(writer, "\n")
+	}
+
+	return nil
+}
+
+// axisValue returns the label for the point fraction of the way up the
+// y-axis, undoing the logarithmic scale if there is one
+func (h *Histogram) axisValue(maxVal float64, fraction float64) string {
+	if h.s.Logarithmic {
+		return fmt.Sprintf("%.0f", math.Expm1(fraction*math.Log1p(maxVal)))
+	}
+	return fmt.Sprintf("%.0f", fraction*maxVal)
+}
+
+// axisLabels returns the lines of text naming each column. Keys that all fit
+// in their column are written across; otherwise they are rotated to run down
+// the page, abbreviated with an ellipsis if they are longer than maxRows.
+func (h *Histogram) axisLabels(pairs pairlist, columnWidth int, maxRows int) []string {
+	longest := 0
+	for _, p := range pairs {
+		if n := utf8.RuneCountInString(p.Key); n > longest {
+			longest = n
+		}
+	}
+
+	if longest < columnWidth || longest == 1 {
+		line := ""
+		for _, p := range pairs {
+			line += Ljust(p.Key, columnWidth)
+		}
+		return []string{strings.TrimRight(line, " ")}
+	}
+
+	rows := longest
+	if rows > maxRows {
+		rows = maxRows
+	}
+	if rows < 1 {
+		// no room to spare, but each key still gets its ellipsis
+		rows = 1
+	}
+	lines := make([][]string, rows)
+	for _, p := range pairs {
+		key := []rune(p.Key)
+		if len(key) > rows {
+			key = append(key[:rows-1], '…')
+		}
+		for row := range lines {
+			cell := " "
+			if row < len(key) {
+				cell = string(key[row])
+			}
+			lines[row] = append(lines[row], cell+strings.Repeat(" ", columnWidth-1))
+		}
+	}
+
+	labels := make([]string, rows)
+	for row, cells := range lines {
+		labels[row] = strings.TrimRight(strings.Join(cells, ""), " ")
+	}
+	return labels
+}
@@ -0,0 +1,85 @@
+package histogram
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestHistogram_WriteVertical(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		counts   map[string]float64
+		expected string
+	}{
+		{
+			name:     "Empty PairList",
+			args:     []string{RC_FILE, WIDTH, "--height=4"},
+			counts:   make(map[string]float64),
+			expected: "",
+		},
+		{
+			name:   "Keys in key order",
+			args:   []string{RC_FILE, WIDTH, "--height=4"},
+			counts: map[string]float64{"10": 2, "9": 8, "a": 5},
+			expected: "8┤███         \n" +
+				" │███     ▄▄▄ \n" +
+				"4┤███     ███ \n" +
+				" │███ ███ ███ \n" +
+				"0└────────────\n" +
+				"  9   10  a\n",
+		},
+		{
+			name:   "Rotated labels",
+			args:   []string{RC_FILE, "--width=6", "--height=3"},
+			counts: map[string]float64{"ab": 3, "cdef": 3},
+			expected: "3┤█ █ \n" +
+				"2┤█ █ \n" +
+				" │█ █ \n" +
+				"0└────\n" +
+				"  a c\n" +
+				"  b d\n" +
+				"    …\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSettings(t, tc.args)
+			h := NewHistogram(s)
+			buf := new(bytes.Buffer)
+
+			if err := h.WriteVertical(buf, tc.counts); err != nil {
+				t.Fatalf("WriteVertical returned an error: %s", err)
+			}
+
+			if buf.String() != tc.expected {
+				t.Errorf("WriteVertical incorrect: expected\n%s\nactual\n%s", tc.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestHistogram_AxisLabels(t *testing.T) {
+	testCases := []struct {
+		name     string
+		maxRows  int
+		expected []string
+	}{
+		{"Rotated", 4, []string{"ac", "bd", " e", " f"}},
+		{"Abbreviated", 2, []string{"ac", "b…"}},
+		{"No rows", 0, []string{"……"}},
+	}
+
+	pairs := pairlist{{"ab", 1}, {"cdef", 1}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHistogram(newSettings(t, []string{RC_FILE}))
+			labels := h.axisLabels(pairs, 1, tc.maxRows)
+			if !reflect.DeepEqual(labels, tc.expected) {
+				t.Errorf("axisLabels incorrect: expected %q; actual %q", tc.expected, labels)
+			}
+		})
+	}
+}
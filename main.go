@@ -1,35 +1,279 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/bradfordboyle/go-distribution/histogram"
 	"github.com/bradfordboyle/go-distribution/settings"
+	"github.com/bradfordboyle/go-distribution/stats"
 	"github.com/bradfordboyle/go-distribution/tokenize"
 )
 
 func main() {
-	s := settings.NewSettings(os.Args[0], os.Args[1:])
+	start := time.Now()
+	log.SetFlags(0)
+	log.SetPrefix(filepath.Base(os.Args[0]) + ": ")
 
-	var t tokenize.Tokenizer
-	if s.GraphValues == "vk" {
-		t = tokenize.NewValueKeyTokenizer()
+	s, err := settings.NewSettings(os.Args[0], os.Args[1:])
+	if err == settings.ErrHelp {
+		s.WriteUsage(os.Stderr)
+		return
+	} else if err != nil {
+		usageError(err)
+	}
+	for _, w := range s.Warnings {
+		log.Print(w)
+	}
+	if len(s.Args) > 0 {
+		usageError(fmt.Errorf("unexpected argument %q: input is read from stdin", s.Args[0]))
+	}
+	h := histogram.NewHistogram(s)
+	h.SetWriters(os.Stderr, os.Stderr)
+	for name, re := range s.Patterns {
+		if err := tokenize.RegisterPattern(name, re); err != nil {
+			usageError(err)
+		}
+	}
+	opts, err := tokenizeOptions(s)
+	if err != nil {
+		usageError(err)
+	}
+	var distinct *tokenize.DistinctCounter
+	if s.Agg == "distinct" {
+		// keep the counter, to report whether its counts are estimates
+		opts = append(opts, tokenize.WithCounter(func() tokenize.Counter {
+			distinct = tokenize.NewDistinctCounter()
+			return distinct
+		}))
+	}
+	// an interrupt stops the reading of stdin, but the --compare file is still
+	// read in full, to have something to compare with. Progress, and the run
+	// statistics, are of stdin alone.
+	stdinOpts := append(append([]tokenize.Option(nil), opts...), progressOption(s), interruptOption())
+
+	if s.GraphValues == "multi" {
+		pc, err := tokenize.NewMultiValueTokenizer(stdinOpts...).TokenizePairs(os.Stdin)
+		if err = truncated(err); err != nil {
+			log.Fatal(err)
+		}
+		h.SetRuntime(time.Since(start))
+		if err := h.WriteGrouped(os.Stdout, pc.Counts, pc.Secondaries); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if s.Output == "heatmap" || s.Output == "stacked" {
+		pt, err := tokenize.NewPairTokenizer(s.PairRegexp, s.MatchRegexp, s.Bucket, stdinOpts...)
+		if err != nil {
+			usageError(err)
+		}
+		pc, err := pt.TokenizePairs(os.Stdin)
+		if err = truncated(err); err != nil {
+			log.Fatal(err)
+		}
+		h.SetRuntime(time.Since(start))
+		if s.Output == "heatmap" {
+			// with --bucket, a column for every bucket, empty or not
+			var columns []string
+			if s.Bucket > 0 {
+				columns = tokenize.BucketRange(pc.Secondaries, s.Bucket)
+			}
+			err = h.WriteHeatmap(os.Stdout, pc.Counts, pc.Totals(), columns)
+		} else {
+			err = h.WriteStacked(os.Stdout, pc.Counts, pc.Totals())
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	t, err := newTokenizer(s, stdinOpts)
+	if err != nil {
+		usageError(err)
+	}
+
+	pl, err := t.Tokenize(os.Stdin)
+	if err = truncated(err); err != nil {
+		log.Fatal(err)
+	}
+	if distinct != nil && distinct.Estimated() {
+		h.SetDistinctError(tokenize.DistinctRelativeError)
+	}
+
+	var sample stats.Sample
+	if s.NumOnly != "XXX" {
+		sample = stats.FromValues(pl)
+	} else if s.MatchRegexp == "num" || s.MatchRegexp == tokenize.PatternSigil+"num" || s.Binned {
+		sample = stats.FromKeys(pl)
+	}
+	if sample != nil {
+		h.SetSummary(stats.Summarize(sample))
+	}
+
+	var edges []float64
+	if s.Binned {
+		binning := stats.Binning{Count: s.Bins, Width: s.BinWidth, Bounds: s.Bounds, Log: s.LogBins}
+		edges, err = sample.Edges(binning)
+		if err != nil {
+			usageError(err)
+		}
+		pl = sample.Bin(edges)
+	}
+
+	if s.Compare != "" {
+		f, err := os.Open(s.Compare)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		ct, err := newTokenizer(s, opts)
+		if err != nil {
+			usageError(err)
+		}
+
+		before, err := ct.Tokenize(f)
+		if err != nil {
+			log.Fatal(err)
+		}
+		h.SetRuntime(time.Since(start))
+		if s.Binned {
+			if s.NumOnly != "XXX" {
+				before = stats.FromValues(before).Bin(edges)
+			} else {
+				before = stats.FromKeys(before).Bin(edges)
+			}
+		}
+		h.WriteDiff(os.Stdout, before, pl)
+		return
+	}
+
+	h.SetRuntime(time.Since(start))
+	if err := h.WriteSnapshot(os.Stdout, pl); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// usageError reports a problem with the flags, or the regexps and formats
+// they give, and exits with status 2
+func usageError(err error) {
+	log.Print(err)
+	os.Exit(2)
+}
+
+// newTokenizer returns the Tokenizer for the input the settings describe
+func newTokenizer(s *settings.Settings, opts []tokenize.Option) (tokenize.Tokenizer, error) {
+	if s.Format != "" {
+		return formatTokenizer(s, opts)
+	} else if s.Agg == "distinct" && len(s.Stages) == 0 {
+		return tokenize.NewDistinctTokenizer(s.PairRegexp, s.MatchRegexp, opts...)
+	} else if len(s.Stages) > 0 {
+		return tokenize.ParsePipeline(s.Stages, opts...)
+	} else if s.GraphValues == "vk" {
+		return tokenize.NewValueKeyTokenizer(opts...), nil
 	} else if s.GraphValues == "kv" {
-		t = tokenize.NewKeyValueTokenizer()
+		return tokenize.NewKeyValueTokenizer(opts...), nil
 	} else if s.NumOnly != "XXX" {
-		os.Exit(0)
+		return tokenize.NewNumericTokenizer(s.NumOnly, s.Rate, opts...), nil
 	} else if s.Tokenize != "" {
-		t = tokenize.NewRegexTokenizer(s.Tokenize, s.MatchRegexp)
-	} else {
-		t = tokenize.NewLineTokenizer(s.MatchRegexp)
+		return tokenize.NewRegexTokenizer(s.Tokenize, s.MatchRegexp, opts...)
 	}
+	return tokenize.NewLineTokenizer(s.MatchRegexp, opts...)
+}
 
-	pl, err := t.Tokenize(os.Stdin)
-	if err != nil {
-		log.Fatal(err)
+// tokenizeOptions returns the options for reading the input given by the
+// settings
+func tokenizeOptions(s *settings.Settings) ([]tokenize.Option, error) {
+	opts := []tokenize.Option{tokenize.WithMaxRecordSize(s.MaxRecordSize)}
+	// main sets up distinct counters itself
+	if s.Agg != "" && s.Agg != "distinct" {
+		newCounter, err := tokenize.NewAggregateCounter(s.Agg)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, tokenize.WithCounter(newCounter))
+	}
+	if s.SeparatorRegexp != "" {
+		re, err := regexp.Compile(s.SeparatorRegexp)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, tokenize.WithSeparator(tokenize.SplitRegexp(re)))
+	} else if s.Separator != "" {
+		opts = append(opts, tokenize.WithSeparator(tokenize.SplitString(s.Separator)))
 	}
+	return opts, nil
+}
 
-	h := histogram.NewHistogram(s)
-	h.WriteHist(os.Stdout, pl)
+// formatTokenizer returns the Tokenizer for the --format of the input
+func formatTokenizer(s *settings.Settings, opts []tokenize.Option) (tokenize.Tokenizer, error) {
+	fields := tokenize.LogFields{Key: s.Field, Value: s.Weight, Distinct: s.Distinct}
+	switch {
+	case s.Format == "common" || s.Format == "combined":
+		return tokenize.NewAccessLogTokenizer(s.Format, fields, s.MatchRegexp, opts...)
+	case strings.HasPrefix(s.Format, "nginx:"):
+		return tokenize.NewAccessLogTokenizer(strings.TrimPrefix(s.Format, "nginx:"), fields, s.MatchRegexp, opts...)
+	case s.Format == "syslog":
+		return tokenize.NewSyslogTokenizer(fields, s.MatchRegexp, opts...)
+	}
+	return nil, fmt.Errorf("unknown format %q", s.Format)
+}
+
+// progressOption has a tokenizer keep the counts reported by --verbose, and
+// show its progress while it reads if stderr is a terminal
+func progressOption(s *settings.Settings) tokenize.Option {
+	var write func(tokenize.Progress)
+	if settings.IsTerminal(os.Stderr) {
+		size := int64(0)
+		if fi, err := os.Stdin.Stat(); err == nil && fi.Mode().IsRegular() {
+			size = fi.Size()
+		}
+		write = tokenize.NewProgressWriter(os.Stderr, size)
+	}
+
+	return tokenize.WithProgress(time.Duration(s.StatInterval), func(p tokenize.Progress) {
+		if write != nil {
+			write(p)
+		}
+		if p.Done {
+			s.TotalObjects, s.TotalValues = uint(p.Examined), p.Matched
+		}
+	})
+}
+
+// interruptOption has a tokenizer stop reading at the first SIGINT or SIGTERM,
+// so that what it has counted can still be shown. A second signal exits at
+// once.
+func interruptOption() tokenize.Option {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+		<-signals
+		os.Exit(130)
+	}()
+
+	return tokenize.WithContext(ctx)
+}
+
+// truncated notes on stderr if reading was interrupted, which is not an error,
+// and returns any other error
+func truncated(err error) error {
+	if err == tokenize.ErrInterrupted {
+		os.Stderr.WriteString("input truncated by interrupt: showing what was counted so far\n")
+		return nil
+	}
+	return err
 }
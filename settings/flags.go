@@ -0,0 +1,458 @@
+package settings
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// option is a command line option. An option takes a value, or may take one
+// if it has a bare value to use when it is given none, or is a switch if it
+// has setBool.
+type option struct {
+	names []string
+	short byte
+	// bare is the value of an option that may take a value, when given none
+	bare     string
+	optional bool
+	set      func(s *Settings, flag string, value string) error
+	setBool  func(s *Settings, on bool)
+}
+
+// negatable reports whether the option is a switch that can be turned off
+// with --no-
+func (o *option) negatable() bool {
+	return o.setBool != nil && o.names[0] != "help"
+}
+
+// options are the command line options, in alphabetical order
+var options = []*option{
+	{names: []string{"agg"}, set: func(s *Settings, flag string, value string) error {
+		s.Agg = strings.ToLower(value)
+		if s.Agg == "avg" || s.Agg == "average" {
+			s.Agg = "mean"
+		}
+		return nil
+	}},
+	{names: []string{"bins"}, set: func(s *Settings, flag string, value string) error {
+		s.Bins = value
+		return nil
+	}},
+	{names: []string{"binwidth"}, set: func(s *Settings, flag string, value string) error {
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return &FlagError{flag, value, "must be a number"}
+		}
+		s.BinWidth = w
+		return nil
+	}},
+	{names: []string{"bounds"}, set: func(s *Settings, flag string, value string) error {
+		s.Bounds = nil
+		for _, bound := range strings.Split(value, ",") {
+			b, err := strconv.ParseFloat(bound, 64)
+			if err != nil {
+				return &FlagError{flag, value, fmt.Sprintf("%q is not a number", bound)}
+			}
+			s.Bounds = append(s.Bounds, b)
+		}
+		return nil
+	}},
+	{names: []string{"bucket"}, set: func(s *Settings, flag string, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return &FlagError{flag, value, "must be a duration such as 5m or 1h"}
+		}
+		s.Bucket = d
+		return nil
+	}},
+	{names: []string{"char"}, short: 'C', set: func(s *Settings, flag string, value string) error {
+		if value == "" {
+			return &FlagError{flag, value, "needs at least one character"}
+		}
+		s.HistogramChar = value
+		return nil
+	}},
+	{names: []string{"color", "colour"}, short: 'c', setBool: func(s *Settings, on bool) {
+		s.ColourisedOutput = on
+	}},
+	{names: []string{"compare"}, set: func(s *Settings, flag string, value string) error {
+		s.Compare = value
+		return nil
+	}},
+	{names: []string{"distinct"}, optional: true, set: func(s *Settings, flag string, value string) error {
+		s.Agg = "distinct"
+		s.Distinct = value
+		return nil
+	}},
+	{names: []string{"field"}, set: func(s *Settings, flag string, value string) error {
+		s.Field = value
+		return nil
+	}},
+	{names: []string{"format"}, set: func(s *Settings, flag string, value string) error {
+		s.Format = value
+		return nil
+	}},
+	// can pass --graph without option, will default to value/key ordering
+	// since unix perfers that for piping-to-sort reasons
+	{names: []string{"graph"}, short: 'g', optional: true, bare: "vk", set: func(s *Settings, flag string, value string) error {
+		s.GraphValues = value
+		return nil
+	}},
+	{names: []string{"height"}, short: 'H', set: func(s *Settings, flag string, value string) error {
+		n, err := parseUint(flag, value)
+		s.HeightArg = n
+		return err
+	}},
+	{names: []string{"help"}, short: 'h', setBool: func(s *Settings, on bool) {}},
+	{names: []string{"keys"}, short: 'k', set: func(s *Settings, flag string, value string) error {
+		n, err := parseUint(flag, value)
+		s.MaxKeys = n
+		return err
+	}},
+	{names: []string{"logarithmic"}, short: 'l', setBool: func(s *Settings, on bool) {
+		s.Logarithmic = on
+	}},
+	{names: []string{"logbins"}, setBool: func(s *Settings, on bool) {
+		s.LogBins = on
+	}},
+	{names: []string{"match"}, short: 'm', set: func(s *Settings, flag string, value string) error {
+		s.MatchRegexp = value
+		return nil
+	}},
+	{names: []string{"maxrecord"}, set: func(s *Settings, flag string, value string) error {
+		n, err := humanize.ParseBytes(value)
+		if err != nil || n == 0 {
+			return &FlagError{flag, value, "must be a size such as 4096 or 16MiB"}
+		}
+		s.MaxRecordSize = int(n)
+		return nil
+	}},
+	{names: []string{"numonly"}, short: 'n', optional: true, bare: "abs", set: func(s *Settings, flag string, value string) error {
+		if value == "" {
+			return &FlagError{flag, value, "must be abs or mon, or a synonym"}
+		}
+		s.NumOnly = value
+		return nil
+	}},
+	{names: []string{"output"}, short: 'o', set: func(s *Settings, flag string, value string) error {
+		switch value {
+		case "text", "svg", "png", "md", "markdown", "html", "vertical", "vert", "v", "spark", "sparkline", "heatmap", "stacked":
+			s.Output = value
+			return nil
+		}
+		return &FlagError{flag, value, "must be text, svg, png, md, html, vertical, spark, heatmap or stacked"}
+	}},
+	{names: []string{"pairs"}, set: func(s *Settings, flag string, value string) error {
+		s.PairRegexp = value
+		return nil
+	}},
+	{names: []string{"palette"}, short: 'p', set: func(s *Settings, flag string, value string) error {
+		s.ColourPalette = value
+		s.ColourisedOutput = true
+		return nil
+	}},
+	{names: []string{"pattern"}, set: func(s *Settings, flag string, value string) error {
+		pattern := strings.SplitN(value, "=", 2)
+		if len(pattern) < 2 || pattern[0] == "" {
+			return &FlagError{flag, value, "needs a name and a regexp, as NAME=REGEXP"}
+		}
+		s.Patterns[pattern[0]] = pattern[1]
+		return nil
+	}},
+	{names: []string{"rate"}, setBool: func(s *Settings, on bool) {
+		s.Rate = on
+	}},
+	{names: []string{"rcfile"}, set: func(s *Settings, flag string, value string) error {
+		return fmt.Errorf("%s must be the first argument", flag)
+	}},
+	{names: []string{"separator"}, set: func(s *Settings, flag string, value string) error {
+		s.Separator = parseSeparator(value)
+		return nil
+	}},
+	{names: []string{"separatorregexp"}, set: func(s *Settings, flag string, value string) error {
+		s.SeparatorRegexp = value
+		return nil
+	}},
+	{names: []string{"seriespalette"}, set: func(s *Settings, flag string, value string) error {
+		s.SeriesPalette = value
+		s.ColourisedOutput = true
+		return nil
+	}},
+	{names: []string{"size"}, short: 's', set: func(s *Settings, flag string, value string) error {
+		s.Size = value
+		return nil
+	}},
+	{names: []string{"sort"}, set: func(s *Settings, flag string, value string) error {
+		s.Sort = value
+		return nil
+	}},
+	{names: []string{"sparkstats"}, setBool: func(s *Settings, on bool) {
+		s.SparkStats = on
+	}},
+	{names: []string{"stage"}, set: func(s *Settings, flag string, value string) error {
+		s.Stages = append(s.Stages, value)
+		return nil
+	}},
+	{names: []string{"tokenize"}, short: 't', set: func(s *Settings, flag string, value string) error {
+		s.Tokenize = value
+		return nil
+	}},
+	{names: []string{"value", "weight"}, set: func(s *Settings, flag string, value string) error {
+		s.Weight = value
+		return nil
+	}},
+	{names: []string{"verbose"}, short: 'v', setBool: func(s *Settings, on bool) {
+		s.Verbose = on
+	}},
+	{names: []string{"width"}, short: 'w', set: func(s *Settings, flag string, value string) error {
+		n, err := parseUint(flag, value)
+		s.WidthArg = n
+		return err
+	}},
+}
+
+// parseUint parses the value of a flag that takes a whole number
+func parseUint(flag string, value string) (uint, error) {
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, &FlagError{flag, value, "must be a whole number"}
+	}
+	return uint(n), nil
+}
+
+// parseSeparator expands the --separator shortcuts and escapes
+func parseSeparator(sep string) string {
+	if sep == "nul" {
+		return "\x00"
+	}
+	if unquoted, err := strconv.Unquote(`"` + sep + `"`); err == nil {
+		return unquoted
+	}
+	return sep
+}
+
+// shortValueOptions are the single-character switches that, given a value
+// after "=", stand for another option, as they did before single-character
+// options could be combined: -c=C is --char and -h=N is --height. They are
+// deprecated in favour of -C and -H, which take a value however it is given.
+var shortValueOptions = map[byte]byte{
+	'c': 'C',
+	'h': 'H',
+}
+
+// parseArgs sets the options given by args. The names of long options may be
+// abbreviated to any unique prefix, and switches turned off with --no-.
+// Single-character options may be combined, as -cv. Anything that is not an
+// option, and everything after "--", is left in s.Args.
+func (s *Settings) parseArgs(args []string) error {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var err error
+		switch {
+		case arg == "--":
+			s.Args = append(s.Args, args[i+1:]...)
+			return nil
+		case strings.HasPrefix(arg, "--"):
+			i, err = s.parseLong(args, i)
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			i, err = s.parseShort(args, i)
+		default:
+			s.Args = append(s.Args, arg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseLong sets the long option args[i], and returns the index of the last
+// argument it used: args[i+1] if that is its value
+func (s *Settings) parseLong(args []string, i int) (int, error) {
+	name, value := args[i][len("--"):], ""
+	hasValue := false
+	if eq := strings.IndexByte(name, '='); eq >= 0 {
+		name, value, hasValue = name[:eq], name[eq+1:], true
+	}
+
+	o, negated, err := lookupOption(name)
+	if err != nil {
+		return i, err
+	}
+	flag := "--" + name
+
+	if o.setBool != nil {
+		on := !negated
+		if hasValue {
+			b, err := strconv.ParseBool(value)
+			if err != nil || negated {
+				return i, &FlagError{flag, value, "takes no value, or true or false"}
+			}
+			on = b
+		}
+		if o.names[0] == "help" {
+			return i, ErrHelp
+		}
+		o.setBool(s, on)
+		return i, nil
+	}
+
+	if !hasValue {
+		if o.optional {
+			return i, o.set(s, flag, o.bare)
+		}
+		if i+1 >= len(args) {
+			return i, fmt.Errorf("option %s needs a value", flag)
+		}
+		i++
+		value = args[i]
+	}
+	return i, o.set(s, flag, value)
+}
+
+// parseShort sets the single-character options combined in args[i], and
+// returns the index of the last argument they used
+func (s *Settings) parseShort(args []string, i int) (int, error) {
+	arg := args[i]
+	for j := 1; j < len(arg); j++ {
+		o := shortOption(arg[j])
+		if o == nil {
+			return i, fmt.Errorf("unknown option -%c in %s", arg[j], arg)
+		}
+		flag := "-" + string(arg[j])
+		rest := arg[j+1:]
+
+		if o.setBool != nil {
+			if strings.HasPrefix(rest, "=") {
+				if c, ok := shortValueOptions[arg[j]]; ok {
+					o = shortOption(c)
+					s.Warnings = append(s.Warnings, fmt.Sprintf("%s%s is deprecated: use -%c%s or --%s%s", flag, rest, c, rest, o.names[0], rest))
+					return i, o.set(s, flag, rest[1:])
+				}
+				return i, &FlagError{flag, rest[1:], "takes no value"}
+			}
+			if o.names[0] == "help" {
+				return i, ErrHelp
+			}
+			o.setBool(s, true)
+			continue
+		}
+
+		// the rest of the argument is the value, or else the next one
+		switch {
+		case strings.HasPrefix(rest, "="):
+			return i, o.set(s, flag, rest[1:])
+		case rest != "" && !o.optional:
+			return i, o.set(s, flag, rest)
+		case o.optional:
+			// any options combined after it are read on
+			if err := o.set(s, flag, o.bare); err != nil {
+				return i, err
+			}
+		case i+1 >= len(args):
+			return i, fmt.Errorf("option %s needs a value", flag)
+		default:
+			return i + 1, o.set(s, flag, args[i+1])
+		}
+	}
+	return i, nil
+}
+
+// lookupOption finds the option called name, or that name is the start of the
+// name of, and whether it was negated with "no-"
+func lookupOption(name string) (*option, bool, error) {
+	var matches []optionMatch
+	var matchNames []string
+
+	for _, exact := range []bool{true, false} {
+		for _, o := range options {
+			for _, n := range o.names {
+				candidates := []string{n}
+				if o.negatable() {
+					candidates = append(candidates, "no-"+n)
+				}
+				for _, c := range candidates {
+					if c == name || (!exact && strings.HasPrefix(c, name)) {
+						m := optionMatch{o, c != n}
+						if !containsMatch(matches, m) {
+							matches = append(matches, m)
+							matchNames = append(matchNames, "--"+c)
+						}
+					}
+				}
+			}
+		}
+		if len(matches) > 0 {
+			break
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, false, fmt.Errorf("unknown option --%s", name)
+	case 1:
+		return matches[0].o, matches[0].negated, nil
+	}
+	sort.Strings(matchNames)
+	return nil, false, fmt.Errorf("ambiguous option --%s: could be %s", name, strings.Join(matchNames, ", "))
+}
+
+// optionMatch is an option a name given on the command line could be, and
+// whether it is negated
+type optionMatch struct {
+	o       *option
+	negated bool
+}
+
+// containsMatch reports whether matches already has m, as when both names of
+// an option start with the name given
+func containsMatch(matches []optionMatch, m optionMatch) bool {
+	for _, other := range matches {
+		if other == m {
+			return true
+		}
+	}
+	return false
+}
+
+// shortOption returns the option with the single-character name c, or nil
+func shortOption(c byte) *option {
+	for _, o := range options {
+		if o.short == c {
+			return o
+		}
+	}
+	return nil
+}
+
+// shortOptionsUsage lists the single-character options and what they stand
+// for, on lines no wider than the rest of the usage
+func shortOptionsUsage() string {
+	var buf bytes.Buffer
+	line := 0
+	for _, o := range options {
+		if o.short == 0 {
+			continue
+		}
+		entry := fmt.Sprintf("-%c for --%s", o.short, o.names[0])
+		if line > 0 && line+len(entry) > 88 {
+			buf.WriteString(",\n")
+			line = 0
+		} else if line > 0 {
+			buf.WriteString(", ")
+			line += 2
+		}
+		if line == 0 {
+			buf.WriteString("  ")
+			line = 2
+		}
+		buf.WriteString(entry)
+		line += len(entry)
+	}
+	return buf.String()
+}
@@ -0,0 +1,133 @@
+package settings
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewSettings_Flags(t *testing.T) {
+	testCases := []struct {
+		args    []string
+		checker func(*Settings) bool
+	}{
+		{[]string{"--width", "90"}, func(s *Settings) bool { return s.Width == 90 }},
+		{[]string{"--width=90"}, func(s *Settings) bool { return s.Width == 90 }},
+		{[]string{"--wid=90"}, func(s *Settings) bool { return s.Width == 90 }},
+		{[]string{"-w", "90"}, func(s *Settings) bool { return s.Width == 90 }},
+		{[]string{"-w90"}, func(s *Settings) bool { return s.Width == 90 }},
+		{[]string{"-w=90"}, func(s *Settings) bool { return s.Width == 90 }},
+		{[]string{"-cvw", "90"}, func(s *Settings) bool { return s.ColourisedOutput && s.Verbose && s.Width == 90 }},
+		{[]string{"-gv"}, func(s *Settings) bool { return s.GraphValues == "vk" && s.Verbose }},
+		{[]string{"-g=kv"}, func(s *Settings) bool { return s.GraphValues == "kv" }},
+		{[]string{"--graph", "kv"}, func(s *Settings) bool { return s.GraphValues == "vk" && fmt.Sprint(s.Args) == "[kv]" }},
+		{[]string{"-c=dt"}, func(s *Settings) bool { return !s.ColourisedOutput && s.HistogramChar == "•" }},
+		{[]string{"-h=15"}, func(s *Settings) bool { return s.Height == 15 }},
+		{[]string{"-C", "|", "-c"}, func(s *Settings) bool { return s.ColourisedOutput && s.HistogramChar == "|" }},
+		{[]string{"-vH15"}, func(s *Settings) bool { return s.Verbose && s.Height == 15 }},
+		{[]string{"--char", "-o"}, func(s *Settings) bool { return s.HistogramChar == "-o" }},
+		{[]string{"--color", "--no-color"}, func(s *Settings) bool { return !s.ColourisedOutput }},
+		{[]string{"--palette=0,0,0,0,0", "--no-col"}, func(s *Settings) bool { return !s.ColourisedOutput }},
+		{[]string{"--colour=false", "--verbose=true"}, func(s *Settings) bool { return !s.ColourisedOutput && s.Verbose }},
+		{[]string{"--weight", "bytes"}, func(s *Settings) bool { return s.Weight == "bytes" }},
+		{[]string{"--separator", "nul"}, func(s *Settings) bool { return s.Separator == "\x00" && s.SeparatorRegexp == "" }},
+		{[]string{"--stage", "lower", "--stage=trim"}, func(s *Settings) bool { return fmt.Sprint(s.Stages) == "[lower trim]" }},
+		{[]string{"-v", "--", "-c", "file"}, func(s *Settings) bool {
+			return s.Verbose && !s.ColourisedOutput && fmt.Sprint(s.Args) == "[-c file]"
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			s, err := NewSettings(t.Name(), append([]string{RC_FILE}, tc.args...))
+			if err != nil {
+				t.Fatalf("NewSettings returned an error: %s", err)
+			}
+			if !tc.checker(s) {
+				t.Errorf("Options '%s' incorrectly handled", strings.Join(tc.args, " "))
+			}
+		})
+	}
+}
+
+func TestNewSettings_FlagErrors(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--frobnicate"}, "unknown option --frobnicate"},
+		{[]string{"--no-width=3"}, "unknown option --no-width"},
+		{[]string{"-x"}, "unknown option -x in -x"},
+		{[]string{"-vx"}, "unknown option -x in -vx"},
+		{[]string{"--s=3"}, "ambiguous option --s: could be --separator, --separatorregexp, --seriespalette, --size, --sort, --sparkstats, --stage"},
+		{[]string{"--lo"}, "ambiguous option --lo: could be --logarithmic, --logbins"},
+		{[]string{"--width"}, "option --width needs a value"},
+		{[]string{"-v", "-w"}, "option -w needs a value"},
+		{[]string{"--verbose=loud"}, `invalid value "loud" for --verbose: takes no value, or true or false`},
+		{[]string{"-v=1"}, `invalid value "1" for -v: takes no value`},
+		{[]string{"--rcfile=/dev/null"}, "--rcfile must be the first argument"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			_, err := NewSettings(t.Name(), append([]string{RC_FILE}, tc.args...))
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("NewSettings error incorrect: expected %v; actual %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestNewSettings_Warnings(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"-C=x"}, nil},
+		{[]string{"-c=x"}, []string{"-c=x is deprecated: use -C=x or --char=x"}},
+		{[]string{"-h=4", "-k=10", "-v"}, []string{"-h=4 is deprecated: use -H=4 or --height=4", "Update MaxKeys to 3004 (height + 3000)"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			s, err := NewSettings(t.Name(), append([]string{RC_FILE}, tc.args...))
+			if err != nil {
+				t.Fatalf("NewSettings returned an error: %s", err)
+			}
+			if !reflect.DeepEqual(s.Warnings, tc.expected) {
+				t.Errorf("Warnings incorrect: expected %q; actual %q", tc.expected, s.Warnings)
+			}
+		})
+	}
+}
+
+func TestNewSettings_RcfileSeparateValue(t *testing.T) {
+	s, err := NewSettings(t.Name(), []string{"--rcfile", "/dev/null", "-v"})
+	if err != nil {
+		t.Fatalf("NewSettings returned an error: %s", err)
+	}
+	if !s.Verbose || len(s.Args) != 0 {
+		t.Errorf("NewSettings incorrect with --rcfile F: verbose %v; args %v", s.Verbose, s.Args)
+	}
+}
+
+func TestNewSettings_Rcfile(t *testing.T) {
+	f, err := ioutil.TempFile("", "distributionrc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# comment\n--color  # trailing comment\n--width 90\n--char=-o\n")
+	f.Close()
+
+	s, err := NewSettings(t.Name(), []string{"--rcfile=" + f.Name(), "--no-color"})
+	if err != nil {
+		t.Fatalf("NewSettings returned an error: %s", err)
+	}
+	if s.ColourisedOutput || s.Width != 90 || s.HistogramChar != "-o" {
+		t.Errorf("rcfile incorrectly handled: color %v; width %v; char %v", s.ColourisedOutput, s.Width, s.HistogramChar)
+	}
+}
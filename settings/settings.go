@@ -3,13 +3,14 @@ package settings
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"os/exec"
 	"os/user"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -17,9 +18,6 @@ import (
 
 type Settings struct {
 	ScriptName       string
-	TotalMillis      uint
-	StartTime        int64
-	EndTime          int64
 	WidthArg         uint
 	HeightArg        uint
 	Width            uint
@@ -33,6 +31,28 @@ type Settings struct {
 	Size             string
 	Tokenize         string
 	MatchRegexp      string
+	Output           string
+	Sort             string
+	SparkStats       bool
+	PairRegexp       string
+	Bucket           time.Duration
+	Compare          string
+	Bins             string
+	BinWidth         float64
+	Bounds           []float64
+	LogBins          bool
+	Rate             bool
+	Binned           bool
+	MaxRecordSize    int
+	Separator        string
+	SeparatorRegexp  string
+	Stages           []string
+	Patterns         map[string]string
+	Format           string
+	Field            string
+	Weight           string
+	Agg              string
+	Distinct         string
 	StatInterval     int
 	NumPrunes        uint
 	ColourPalette    string
@@ -41,6 +61,9 @@ type Settings struct {
 	CtColour         string
 	PctColour        string
 	GraphColour      string
+	NegativeColour   string
+	SeriesPalette    string
+	SeriesColours    []string
 	TotalObjects     uint
 	TotalValues      uint64
 	KeyPruneInterval uint
@@ -50,15 +73,37 @@ type Settings struct {
 	GraphChars       []string
 	PartialBlocks    []string
 	PartialLines     []string
+	PartialColumns   []string
+	// Args are the arguments that are not options
+	Args []string
+	// Warnings are messages about the settings, such as a deprecated flag,
+	// for the caller to show
+	Warnings []string
 }
 
-func NewSettings(scriptName string, args []string) *Settings {
+// ErrHelp is returned by NewSettings when --help is given. The Settings are
+// returned with it so that their usage can be written.
+var ErrHelp = errors.New("help requested")
+
+// FlagError is a value given to a flag that cannot be used
+type FlagError struct {
+	// Flag is the flag as it was given, eg "-w" or "--width"
+	Flag   string
+	Value  string
+	Reason string
+}
+
+func (e *FlagError) Error() string {
+	return fmt.Sprintf("invalid value %q for %s: %s", e.Value, e.Flag, e.Reason)
+}
+
+// NewSettings returns the settings given by args, after those in the rcfile.
+// A flag whose value cannot be used is reported as a *FlagError, and an
+// option that is unknown, or an abbreviation of more than one, as an error.
+func NewSettings(scriptName string, args []string) (*Settings, error) {
 	// default settings
 	s := &Settings{
 		ScriptName:       scriptName,
-		TotalMillis:      0,
-		StartTime:        time.Now().UnixNano(),
-		EndTime:          0,
 		WidthArg:         0,
 		HeightArg:        0,
 		Width:            80,
@@ -72,6 +117,22 @@ func NewSettings(scriptName string, args []string) *Settings {
 		Size:             "",
 		Tokenize:         "",
 		MatchRegexp:      ".",
+		Output:           "text",
+		Sort:             "",
+		SparkStats:       false,
+		PairRegexp:       "",
+		Bucket:           0,
+		Compare:          "",
+		MaxRecordSize:    1024 * 1024,
+		Separator:        "",
+		SeparatorRegexp:  "",
+		Stages:           []string{},
+		Patterns:         map[string]string{},
+		Format:           "",
+		Field:            "",
+		Weight:           "",
+		Agg:              "",
+		Distinct:         "",
 		StatInterval:     1e9,
 		NumPrunes:        0,
 		ColourPalette:    "0,0,32,35,34",
@@ -80,6 +141,9 @@ func NewSettings(scriptName string, args []string) *Settings {
 		CtColour:         "",
 		PctColour:        "",
 		GraphColour:      "",
+		NegativeColour:   "",
+		SeriesPalette:    "31,32,33,34,35,36",
+		SeriesColours:    []string{},
 		TotalObjects:     0,
 		TotalValues:      0,
 		KeyPruneInterval: 1500000,
@@ -89,16 +153,27 @@ func NewSettings(scriptName string, args []string) *Settings {
 		GraphChars:       []string{},
 		PartialBlocks:    []string{"▏", "▎", "▍", "▌", "▋", "▊", "▉", "█"},
 		PartialLines:     []string{"╸", "╾", "━"},
+		PartialColumns:   []string{"▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"},
 	}
 
 	// rcfile grabbing/parsing if specified
 	var rcFile string
 	if len(args) > 0 && strings.HasPrefix(args[0], "--rcfile") {
-		rcFile = strings.Split(args[0], "=")[1]
+		argList := strings.SplitN(args[0], "=", 2)
+		args = args[1:]
+		if len(argList) < 2 && len(args) > 0 {
+			// --rcfile F
+			argList = append(argList, args[0])
+			args = args[1:]
+		}
+		if len(argList) < 2 || argList[1] == "" {
+			return nil, &FlagError{argList[0], "", "needs a file name"}
+		}
+		rcFile = argList[1]
 	} else {
 		u, err := user.Current()
 		if err != nil {
-			log.Fatal(err)
+			return nil, fmt.Errorf("cannot find the home directory for .distributionrc: %s", err)
 		}
 		rcFile = path.Join(u.HomeDir, ".distributionrc")
 	}
@@ -110,76 +185,33 @@ func NewSettings(scriptName string, args []string) *Settings {
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		line := scanner.Text()
-		trimmedLine := strings.TrimRight(line, " ")
-		rcOpt := strings.Split(trimmedLine, "#")[0]
-		if rcOpt != "" {
-			args = append(args, "")
-			copy(args[1:], args[0:])
-			args[0] = rcOpt
+		rcOpt := strings.TrimSpace(strings.Split(scanner.Text(), "#")[0])
+		if rcOpt == "" {
+			continue
+		}
+		// "--width 90" is two arguments, as on the command line
+		rcArgs := []string{rcOpt}
+		if !strings.Contains(rcOpt, "=") {
+			rcArgs = strings.Fields(rcOpt)
 		}
+		args = append(rcArgs, args...)
 	}
 
-	// manual argument parsing
-	for _, arg := range args {
-		if arg == "-h" || arg == "--help" {
-			doUsage(s, os.Stderr)
-			os.Exit(0)
-		} else if arg == "-c" || arg == "--color" || arg == "--colour" {
-			s.ColourisedOutput = true
-		} else if arg == "-g" || arg == "--graph" {
-			// can pass --graph without option, will default to value/key ordering
-			// since unix perfers that for piping-to-sort reasons
-			s.GraphValues = "vk"
-		} else if arg == "-l" || arg == "--logarithmic" {
-			s.Logarithmic = true
-		} else if arg == "-n" || arg == "--numonly" {
-			s.NumOnly = "abs"
-		} else if arg == "-v" || arg == "--verbose" {
-			s.Verbose = true
-		} else {
-			argList := strings.SplitN(arg, "=", 2)
-			if argList[0] == "-w" || argList[0] == "--width" {
-				argInt, err := strconv.ParseUint(argList[1], 10, 32)
-				if err != nil {
-					log.Fatal(err)
-				}
-				s.WidthArg = uint(argInt)
-			} else if argList[0] == "-h" || argList[0] == "--height" {
-				argInt, err := strconv.ParseUint(argList[1], 10, 32)
-				if err != nil {
-					log.Fatal(err)
-				}
-				s.HeightArg = uint(argInt)
-			} else if argList[0] == "-k" || argList[0] == "--keys" {
-				argInt, err := strconv.ParseUint(argList[1], 10, 32)
-				if err != nil {
-					log.Fatal(err)
-				}
-				s.MaxKeys = uint(argInt)
-			} else if argList[0] == "-c" || argList[0] == "--char" {
-				s.HistogramChar = argList[1]
-			} else if argList[0] == "-g" || argList[0] == "--graph" {
-				s.GraphValues = argList[1]
-			} else if argList[0] == "-n" || argList[0] == "--numonly" {
-				s.NumOnly = argList[1]
-			} else if argList[0] == "-p" || argList[0] == "--palette" {
-				s.ColourPalette = argList[1]
-				s.ColourisedOutput = true
-			} else if argList[0] == "-s" || argList[0] == "--size" {
-				s.Size = argList[1]
-			} else if argList[0] == "-t" || argList[0] == "--tokenize" {
-				s.Tokenize = argList[1]
-			} else if argList[0] == "-m" || argList[0] == "--match" {
-				s.MatchRegexp = argList[1]
-			}
+	if err := s.parseArgs(args); err != nil {
+		if err == ErrHelp {
+			return s, err
 		}
+		return nil, err
 	}
 
 	// first, size, which might be further overridden by width/height later
 	if s.Size == "full" || s.Size == "fl" || s.Size == "f" {
 		// tput will tell us the term width/height even if input is stdin
-		s.Width, s.Height = TerminalSize()
+		width, height, err := TerminalSize()
+		if err != nil {
+			return nil, fmt.Errorf("cannot size the histogram to the terminal for --size=%s: %s", s.Size, err)
+		}
+		s.Width, s.Height = width, height
 		s.Height -= 3
 		// need room for the verbosity output
 		if s.Verbose {
@@ -199,6 +231,11 @@ func NewSettings(scriptName string, args []string) *Settings {
 		s.Width, s.Height = 140, 35
 	}
 
+	// rates are of differences between counter readings
+	if s.Rate && s.NumOnly == "XXX" {
+		s.NumOnly = "mon"
+	}
+
 	// synonyms "monotonically-increasing": derivative, difference, delta, increasing
 	// so all "d" "i" and "m" words will be graphing those differences
 	if s.NumOnly[0] == 'd' || s.NumOnly[0] == 'i' || s.NumOnly[0] == 'm' {
@@ -209,6 +246,12 @@ func NewSettings(scriptName string, args []string) *Settings {
 	if s.NumOnly[0] == 'a' || s.NumOnly[0] == 'n' {
 		s.NumOnly = "abs"
 	}
+	// the keys of numeric input are just positions, and bins are ranges, so
+	// keep them in order
+	s.Binned = s.Bins != "" || s.BinWidth != 0 || len(s.Bounds) > 0 || s.LogBins
+	if (s.NumOnly != "XXX" || s.Binned) && s.Sort == "" {
+		s.Sort = "key"
+	}
 
 	// override variables if they were explicitly given
 	if s.WidthArg != 0 {
@@ -223,13 +266,23 @@ func NewSettings(scriptName string, args []string) *Settings {
 	if s.MaxKeys < s.Height+3000 {
 		s.MaxKeys = s.Height + 3000
 		if s.Verbose {
-			os.Stderr.WriteString(fmt.Sprintf("Update MaxKeys to %d (height + 3000)\n", s.MaxKeys))
+			s.Warnings = append(s.Warnings, fmt.Sprintf("Update MaxKeys to %d (height + 3000)", s.MaxKeys))
 		}
 	}
 
+	if err := s.checkRegexps(); err != nil {
+		return nil, err
+	}
+	if err := s.checkAgg(); err != nil {
+		return nil, err
+	}
+
 	// colour palette
 	if s.ColourisedOutput {
 		cl := strings.Split(s.ColourPalette, ",")
+		if len(cl) < 5 {
+			return nil, &FlagError{"--palette", s.ColourPalette, "needs five colours, as r,k,c,p,g[,n]"}
+		}
 		// ANSI color code is ESC+[+NN+m where ESC=chr(27), [ and m are
 		// the literal characters, and NN is a two-digit number, typically
 		// from 31 to 37 - why is this knowledge still useful in 2014?
@@ -238,6 +291,18 @@ func NewSettings(scriptName string, args []string) *Settings {
 		s.CtColour = fmt.Sprintf("\u001b[%sm", cl[2])
 		s.PctColour = fmt.Sprintf("\u001b[%sm", cl[3])
 		s.GraphColour = fmt.Sprintf("\u001b[%sm", cl[4])
+		// bars for negative values are red unless the palette says otherwise
+		negative := "31"
+		if len(cl) > 5 {
+			negative = cl[5]
+		}
+		s.NegativeColour = fmt.Sprintf("\u001b[%sm", negative)
+
+		// one colour for each segment of a stacked bar, repeating if
+		// there are more segments than colours
+		for _, c := range strings.Split(s.SeriesPalette, ",") {
+			s.SeriesColours = append(s.SeriesColours, fmt.Sprintf("\u001b[%sm", c))
+		}
 	}
 
 	// some useful ASCII-->utf-8 substitutions
@@ -279,17 +344,65 @@ func NewSettings(scriptName string, args []string) *Settings {
 		s.UnicodeMode = true
 	}
 
-	return s
+	return s, nil
+}
+
+// checkAgg reports an --agg (or --distinct) given with a way of reading the
+// input that does not aggregate by key, where it would have no effect
+func (s *Settings) checkAgg() error {
+	if s.Agg == "" {
+		return nil
+	}
+	other := ""
+	switch {
+	case s.NumOnly != "XXX":
+		other = "--numonly"
+	case s.GraphValues == "multi":
+		other = "--graph=multi"
+	case s.Output == "heatmap" || s.Output == "stacked":
+		other = "--output=" + s.Output
+	default:
+		return nil
+	}
+	return &FlagError{"--agg", s.Agg, "cannot be used with " + other}
+}
+
+// checkRegexps reports any regexp given to a flag that does not compile. A
+// named pattern, as "@name", compiles as it is and is left for the tokenizers
+// to expand.
+func (s *Settings) checkRegexps() error {
+	for name, re := range s.Patterns {
+		if _, err := regexp.Compile(re); err != nil {
+			return &FlagError{"--pattern", name + "=" + re, err.Error()}
+		}
+	}
+
+	flags := []string{"--match", "--tokenize", "--pairs", "--separatorregexp"}
+	for i, re := range []string{s.MatchRegexp, s.Tokenize, s.PairRegexp, s.SeparatorRegexp} {
+		if _, err := regexp.Compile(re); err != nil {
+			return &FlagError{flags[i], re, err.Error()}
+		}
+	}
+	return nil
+}
+
+// WriteUsage writes the usage of the command line tool, as for --help
+func (s *Settings) WriteUsage(writer io.Writer) {
+	doUsage(s, writer)
 }
 
 func doUsage(s *Settings, writer io.Writer) {
 	io.WriteString(writer, "")
 	io.WriteString(writer, fmt.Sprintf("usage: <commandWithOutput> | %s\n", s.ScriptName))
 	io.WriteString(writer, "         [--size={sm|med|lg|full} | --width=<width> --height=<height>]\n")
-	io.WriteString(writer, "         [--color] [--palette=r,k,c,p,g]\n")
-	io.WriteString(writer, "         [--Tokenize=<tokenChar>]\n")
-	io.WriteString(writer, "         [--graph[=[kv|vk]] [--numonly[=derivative,diff|abs,absolute,actual]]\n")
-	io.WriteString(writer, "         [--char=<barChars>|<substitutionString>]\n")
+	io.WriteString(writer, "         [--color] [--palette=r,k,c,p,g[,n]]\n")
+	io.WriteString(writer, "         [--tokenize=<RE>]\n")
+	io.WriteString(writer, "         [--graph[=[kv|vk|multi]] [--numonly[=derivative,diff|abs,absolute,actual]] [--rate]\n")
+	io.WriteString(writer, "         [--char=<barChars>|<substitutionString>] [--output=<format>]\n")
+	io.WriteString(writer, "         [--compare=<file>] [--bins=<N|sturges|fd>|--binwidth=<W>|--bounds=<B>] [--logbins]\n")
+	io.WriteString(writer, "         [--separator=<S>|--separatorregexp=<RE>] [--maxrecord=<N>]\n")
+	io.WriteString(writer, "         [--stage=<S> ...] [--format=<F> [--field=<F>] [--value=<F>]] [--agg=<A>]\n")
+	io.WriteString(writer, "         [--distinct[=<F>]]\n")
 	io.WriteString(writer, "         [--help] [--verbose]\n")
 	io.WriteString(writer, fmt.Sprintf("  --keys=K       every %d values added, prune hash to K keys (default 5000)\n", s.KeyPruneInterval))
 	io.WriteString(writer, "  --char=C       character(s) to use for histogram character, some substitutions follow:\n")
@@ -302,47 +415,140 @@ func doUsage(s *Settings, writer io.Writer) {
 	io.WriteString(writer, "        di       (♦) Diamond\n")
 	io.WriteString(writer, "        dt       (•) Dot\n")
 	io.WriteString(writer, "        sq       (□) Square\n")
+	io.WriteString(writer, "  --agg=A        graph an aggregate of the values for each key (from --value, --graph or an\n")
+	io.WriteString(writer, "                 extract stage) rather than their sum, or the last of them for --graph:\n")
+	io.WriteString(writer, "        sum      total\n")
+	io.WriteString(writer, "        mean     average (avg is the same)\n")
+	io.WriteString(writer, "        min, max smallest or largest value\n")
+	io.WriteString(writer, "        count    number of values\n")
+	io.WriteString(writer, "        last     last value\n")
+	io.WriteString(writer, "        distinct number of distinct secondary keys (see --distinct)\n")
+	io.WriteString(writer, "        pN       N'th percentile, eg p95 or p99.9 (median is p50)\n")
+	io.WriteString(writer, "                 only sum, count and distinct show a Pct column. not for --numonly,\n")
+	io.WriteString(writer, "                 --graph=multi or --output=heatmap|stacked\n")
+	io.WriteString(writer, "  --bins=N       count numeric input (tokens, or values with --numonly) in N bins rather than\n")
+	io.WriteString(writer, "                 as distinct keys, shown as [lo, hi) ranges. N can also be a rule:\n")
+	io.WriteString(writer, "        sturges  log2 of the number of values, plus one (default, eg with just --logbins)\n")
+	io.WriteString(writer, "        fd       Freedman-Diaconis, from the interquartile range\n")
+	io.WriteString(writer, "  --binwidth=W   bins W wide, aligned to multiples of W (with --logbins, each bin W times wider)\n")
+	io.WriteString(writer, "  --bounds=B     comma-separated list of bin edges, eg 0,10,100,1000\n")
+	io.WriteString(writer, "  --bucket=D     round timestamps used as secondary keys down to a multiple of duration D, eg 1m\n")
 	io.WriteString(writer, "  --color        colourise the output\n")
+	io.WriteString(writer, "  --compare=F    show how the input differs from file F, which is tokenized the same way\n")
+	io.WriteString(writer, "  --distinct[=F] count the distinct secondary keys of each key, taken as for --pairs, or the\n")
+	io.WriteString(writer, "                 distinct values of field F with --format. exact up to 1,000 for a key, then\n")
+	io.WriteString(writer, "                 estimated with HyperLogLog (--verbose shows the error)\n")
+	io.WriteString(writer, "  --field=F      with --format, count the values of field F. for access logs (default path):\n")
+	io.WriteString(writer, "                 remote_addr, remote_user, time, method, path (less any query string),\n")
+	io.WriteString(writer, "                 protocol, status, bytes, referrer, user_agent, or any nginx variable\n")
+	io.WriteString(writer, "                 in the format, eg request_time. for syslog (default app-name):\n")
+	io.WriteString(writer, "                 timestamp, host, app-name, pid, msgid, facility, severity, message,\n")
+	io.WriteString(writer, "                 sd (structured data SD-IDs), sd:NAME or sd:ID:NAME (parameter values)\n")
+	io.WriteString(writer, "  --format=F     input is a log in format F:\n")
+	io.WriteString(writer, "        common   Common Log Format\n")
+	io.WriteString(writer, "        combined Combined Log Format, with the referrer and user agent\n")
+	io.WriteString(writer, "        nginx:LF nginx log_format string LF, eg 'nginx:$remote_addr [$time_local] $request_time'\n")
+	io.WriteString(writer, "        syslog   RFC 3164 (as in /var/log/syslog) or RFC 5424 syslog\n")
 	io.WriteString(writer, "  --graph[=G]    input is already key/value pairs. vk is default:\n")
 	io.WriteString(writer, "        kv       input is ordered key then value\n")
 	io.WriteString(writer, "        vk       input is ordered value then key\n")
+	io.WriteString(writer, "        multi    input is a key then a value for each series, optionally after a header\n")
+	io.WriteString(writer, "                 line naming the series (start it with # if the names are numbers);\n")
+	io.WriteString(writer, "                 draws a bar for each (see --seriespalette)\n")
 	io.WriteString(writer, "  --height=N     height of histogram, headers non-inclusive, overrides --size\n")
 	io.WriteString(writer, "  --help         get help\n")
 	io.WriteString(writer, "  --logarithmic  logarithmic graph\n")
+	io.WriteString(writer, "  --logbins      bins evenly spaced on a logarithmic scale, for positive values\n")
 	io.WriteString(writer, "  --match=RE     only match lines (or tokens) that match this regexp, some substitutions follow:\n")
 	io.WriteString(writer, "        word     ^[A-Z,a-z]+\\$ - tokens/lines must be entirely alphabetic\n")
 	io.WriteString(writer, "        num      ^\\d+\\$        - tokens/lines must be entirely numeric\n")
+	io.WriteString(writer, "                 or @ and the name of a pattern (see --pattern), eg @ipv4, which must match\n")
+	io.WriteString(writer, "                 all of the token/line\n")
+	io.WriteString(writer, "  --maxrecord=N  longest line or record to read, in bytes, eg 16MiB (default 1MiB). a longer\n")
+	io.WriteString(writer, "                 one is an error\n")
 	io.WriteString(writer, "  --numonly[=N]  input is numerics, simply graph values without labels\n")
 	io.WriteString(writer, "        actual   input is just values (default - abs, absolute are synonymous to actual)\n")
-	io.WriteString(writer, "        diff     input monotonically-increasing, graph differences (of 2nd and later values)\n")
+	io.WriteString(writer, "        diff     input monotonically-increasing, graph differences (of 2nd and later values).\n")
+	io.WriteString(writer, "                 a value lower than the one before is taken as a counter reset\n")
+	io.WriteString(writer, "  --output=F     write the histogram in format F instead of as text:\n")
+	io.WriteString(writer, "        svg      standalone SVG image\n")
+	io.WriteString(writer, "        png      PNG image\n")
+	io.WriteString(writer, "        md       Markdown table\n")
+	io.WriteString(writer, "        html     self-contained HTML page with sortable columns\n")
+	io.WriteString(writer, "        vertical column chart with keys along the bottom, best for time-ordered keys\n")
+	io.WriteString(writer, "        spark    one-line sparkline, one character per key\n")
+	io.WriteString(writer, "        heatmap  matrix of key by secondary key (see --pairs), shaded by count\n")
+	io.WriteString(writer, "        stacked  bars split into a segment for each secondary key (see --pairs)\n")
+	io.WriteString(writer, "  --pairs=RE     regexp capturing the key and secondary key for --output=heatmap|stacked (default:\n")
+	io.WriteString(writer, "                 first field of the line, then the rest of the line)\n")
 	io.WriteString(writer, "  --palette=P    comma-separated list of ANSI colour values for portions of the output\n")
-	io.WriteString(writer, "                 in this order: regular, key, count, percent, graph. implies --color.\n")
+	io.WriteString(writer, "                 in this order: regular, key, count, percent, graph and, optionally,\n")
+	io.WriteString(writer, "                 negative (bars for values below zero, default 31). implies --color.\n")
+	io.WriteString(writer, "  --pattern=N=RE name regexp RE N, for --match=@N, --tokenize=@N and --stage, eg in the rcfile:\n")
+	io.WriteString(writer, "                 --pattern=ticket=\\bOPS-\\d+\\b. these are built in:\n")
+	io.WriteString(writer, "        word, num, number, hex, ipv4, ipv6, mac, email, url, hostname, path, uuid,\n")
+	io.WriteString(writer, "        http-status, iso-date, iso-timestamp\n")
+	io.WriteString(writer, "  --rate         input is a timestamp then a counter reading, graph differences per second.\n")
+	io.WriteString(writer, "                 implies --numonly=diff\n")
 	io.WriteString(writer, "  --rcfile=F     use this rcfile instead of ~/.distributionrc - must be first argument!\n")
+	io.WriteString(writer, "  --separator=S  records end at string S rather than at line ends. \\t, \\n and other Go escapes\n")
+	io.WriteString(writer, "                 are allowed, and:\n")
+	io.WriteString(writer, "        nul      the NUL character, as from find -print0 or xargs -0\n")
+	io.WriteString(writer, "  --separatorregexp=RE records end at each match of regexp RE rather than at line ends\n")
+	io.WriteString(writer, "  --seriespalette=P comma-separated list of ANSI colour values for the segments of stacked\n")
+	io.WriteString(writer, "                 bars and the series of --graph=multi, used in turn. implies --color.\n")
 	io.WriteString(writer, "  --size=S       size of histogram, can abbreviate to single character, overridden by --width/--height\n")
 	io.WriteString(writer, "        small    40x10\n")
 	io.WriteString(writer, "        medium   80x20\n")
 	io.WriteString(writer, "        large    120x30\n")
 	io.WriteString(writer, "        full     terminal width x terminal height (approximately)\n")
-	io.WriteString(writer, "  --Tokenize=RE  split input on regexp RE and make histogram of all resulting tokens\n")
+	io.WriteString(writer, "  --sort=S       order of the keys shown (the highest values are always the ones shown):\n")
+	io.WriteString(writer, "        count    highest value first (default, except for --output=vertical and spark)\n")
+	io.WriteString(writer, "        key      by key, numerically where keys are numbers\n")
+	io.WriteString(writer, "  --sparkstats   follow --output=spark with the minimum, maximum and last values\n")
+	io.WriteString(writer, "  --stage=S      count input with a pipeline of stages, one for each --stage, in place of\n")
+	io.WriteString(writer, "                 --tokenize, --match and --graph. filters and transforms apply in order:\n")
+	io.WriteString(writer, "        split:RE   split lines at RE, or white or word as for --tokenize\n")
+	io.WriteString(writer, "        extract:RE key from the group named key, value (if any) from the group named value,\n")
+	io.WriteString(writer, "                   or from the first and second groups if none is named, or kv or vk\n")
+	io.WriteString(writer, "                   as for --graph\n")
+	io.WriteString(writer, "        match:RE   count keys matching RE, or word or num as for --match\n")
+	io.WriteString(writer, "        exclude:RE drop keys matching RE\n")
+	io.WriteString(writer, "        lower, upper, trim  change the case of keys or trim space from them\n")
+	io.WriteString(writer, "        bucket:D   round keys that are timestamps down to a multiple of duration D\n")
+	io.WriteString(writer, "        count:C    sum values for each key (default), or keep the last\n")
+	io.WriteString(writer, "  --tokenize=RE  split input on regexp RE and make histogram of all resulting tokens\n")
 	io.WriteString(writer, "        word     [^\\w] - split on non-word characters like colons, brackets, commas, etc\n")
 	io.WriteString(writer, "        white    \\s    - split on whitespace\n")
+	io.WriteString(writer, "                 or @ and the name of a pattern (see --pattern): tokens are its matches,\n")
+	io.WriteString(writer, "                 eg @ipv4\n")
+	io.WriteString(writer, "  --value=F      with --format, count each line by the value of field F, eg bytes or\n")
+	io.WriteString(writer, "                 request_time, rather than once (see --agg). --weight is the same\n")
 	io.WriteString(writer, "  --width=N      width of the histogram report, N characters, overrides --size\n")
-	io.WriteString(writer, "  --verbose      be verbose\n")
+	io.WriteString(writer, "  --verbose      be verbose, with summary statistics of numeric input (--match=num or\n                 --numonly)\n")
 	io.WriteString(writer, "\n")
-	io.WriteString(writer, "You can use single-characters options, like so: -h=25 -w=20 -v. You must still include the =\n")
+	io.WriteString(writer, "Options take a value as --width=90 or --width 90, and can be shortened to any unique prefix,\n")
+	io.WriteString(writer, "eg --wid=90. Switches such as --color can be turned off with --no-, eg --no-color in place\n")
+	io.WriteString(writer, "of --color in the rcfile. -- ends the options.\n")
+	io.WriteString(writer, fmt.Sprintf("Single-character options:\n%s\n", shortOptionsUsage()))
+	io.WriteString(writer, "They can be combined, as -cv, and take a value as -w 90, -w90 or -w=90. -c=C for --char=C\n")
+	io.WriteString(writer, "and -h=N for --height=N still work, but are deprecated: use -C and -H.\n")
 	io.WriteString(writer, "\n")
 	io.WriteString(writer, "Samples:\n")
 	io.WriteString(writer, fmt.Sprintf("  du -sb /etc/* | %s --palette=0,37,34,33,32 --graph\n", s.ScriptName))
 	io.WriteString(writer, fmt.Sprintf("  du -sk /etc/* | awk '{print $2\" \"$1}' | %s --graph=kv\n", s.ScriptName))
-	io.WriteString(writer, fmt.Sprintf("  zcat /var/log/syslog*gz | %s --char=o --Tokenize=white\n", s.ScriptName))
-	io.WriteString(writer, fmt.Sprintf("  zcat /var/log/syslog*gz | awk '{print \\$5}'  | %s -t=word -m-word -h=15 -c=/\n", s.ScriptName))
-	io.WriteString(writer, fmt.Sprintf("  zcat /var/log/syslog*gz | cut -c 1-9        | %s -width=60 -height=10 -char=em\n", s.ScriptName))
-	io.WriteString(writer, fmt.Sprintf("  find /etc -type f       | cut -c 6-         | %s -Tokenize=/ -w=90 -h=35 -c=dt\n", s.ScriptName))
-	io.WriteString(writer, fmt.Sprintf("  cat /usr/share/dict/words | awk '{print length(\\$1)}' | %s -c=* -w=50 -h=10 | sort -n\n", s.ScriptName))
+	io.WriteString(writer, fmt.Sprintf("  zcat /var/log/syslog*gz | %s --char=o --tokenize=white\n", s.ScriptName))
+	io.WriteString(writer, fmt.Sprintf("  zcat /var/log/syslog*gz | %s --format=syslog --field=app-name\n", s.ScriptName))
+	io.WriteString(writer, fmt.Sprintf("  zcat /var/log/syslog*gz | awk '{print \\$5}'  | %s -t word -m word --height 15 --char /\n", s.ScriptName))
+	io.WriteString(writer, fmt.Sprintf("  zcat /var/log/syslog*gz | cut -c 1-9        | %s --width 60 --height 10 --char em\n", s.ScriptName))
+	io.WriteString(writer, fmt.Sprintf("  awk '{print $5}' /var/log/syslog.1 > before; awk '{print $5}' /var/log/syslog | %s --compare=before\n", s.ScriptName))
+	io.WriteString(writer, fmt.Sprintf("  find /etc -type f       | cut -c 6-         | %s -t / -w 90 --height 35 --char dt\n", s.ScriptName))
+	io.WriteString(writer, fmt.Sprintf("  cat /usr/share/dict/words | awk '{print length(\\$1)}' | %s --char='*' -w50 --height=10 | sort -n\n", s.ScriptName))
 	io.WriteString(writer, "\n")
 }
 
-func TerminalSize() (uint, uint) {
+// TerminalSize returns the width and height of the terminal, as told by stty
+func TerminalSize() (uint, uint, error) {
 
 	stdErr := new(bytes.Buffer)
 	cmd := exec.Command("stty", "size")
@@ -355,20 +561,29 @@ func TerminalSize() (uint, uint) {
 
 	cmdOut, err := cmd.Output()
 	if err != nil {
-		log.Fatalf("Error: `%s`; Command stderr: %#v", err.Error(), stdErr.String())
+		return 0, 0, fmt.Errorf("stty size: %s: %s", err, strings.TrimSpace(stdErr.String()))
 	}
 
-	columnLinesStr := strings.Split(strings.TrimRight(string(cmdOut), "\n"), " ")
+	columnLinesStr := strings.Fields(string(cmdOut))
+	if len(columnLinesStr) != 2 {
+		return 0, 0, fmt.Errorf("stty size: unexpected output %q", cmdOut)
+	}
 
 	lines, err := strconv.ParseUint(columnLinesStr[0], 10, 16)
 	if err != nil {
-		log.Fatal(err)
+		return 0, 0, fmt.Errorf("stty size: unexpected output %q", cmdOut)
 	}
 
 	columns, err := strconv.ParseUint(columnLinesStr[1], 10, 16)
 	if err != nil {
-		log.Fatal(err)
+		return 0, 0, fmt.Errorf("stty size: unexpected output %q", cmdOut)
 	}
 
-	return uint(columns), uint(lines)
+	return uint(columns), uint(lines), nil
+}
+
+// IsTerminal reports whether f is a terminal rather than a file or a pipe
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
 }
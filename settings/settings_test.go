@@ -2,7 +2,12 @@ package settings
 
 import (
 	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 const RC_FILE = "--rcfile=/dev/null"
@@ -14,6 +19,7 @@ func TestNewSettings(t *testing.T) {
 	}{
 		{"--color", func(s *Settings) bool { return s.ColourisedOutput }},
 		{"", func(s *Settings) bool { return s.GraphValues == "" }},
+		{"", func(s *Settings) bool { return s.Output == "text" }},
 		{"-g", func(s *Settings) bool { return s.GraphValues == "vk" }},
 		{"--graph", func(s *Settings) bool { return s.GraphValues == "vk" }},
 		{"-l", func(s *Settings) bool { return s.Logarithmic }},
@@ -34,6 +40,7 @@ func TestNewSettings(t *testing.T) {
 		{"--graph=kv", func(s *Settings) bool { return s.GraphValues == "kv" }},
 		{"-g=vk", func(s *Settings) bool { return s.GraphValues == "vk" }},
 		{"--graph=vk", func(s *Settings) bool { return s.GraphValues == "vk" }},
+		{"--graph=multi", func(s *Settings) bool { return s.GraphValues == "multi" }},
 		{"-n=actual", func(s *Settings) bool { return s.NumOnly == "abs" }},
 		{"--numonly=actual", func(s *Settings) bool { return s.NumOnly == "abs" }},
 		{"-n=n", func(s *Settings) bool { return s.NumOnly == "abs" }},
@@ -44,8 +51,11 @@ func TestNewSettings(t *testing.T) {
 		{"--numonly=m", func(s *Settings) bool { return s.NumOnly == "mon" }},
 		{"-n=diff", func(s *Settings) bool { return s.NumOnly == "mon" }},
 		{"--numonly=m", func(s *Settings) bool { return s.NumOnly == "mon" }},
+		{"--numonly", func(s *Settings) bool { return s.Sort == "key" }},
 		{"-p=30,31,32,33,34", func(s *Settings) bool { return s.ColourPalette == "30,31,32,33,34" && s.ColourisedOutput }},
 		{"--palette=30,31,32,33,34", func(s *Settings) bool { return s.ColourPalette == "30,31,32,33,34" && s.ColourisedOutput }},
+		{"--palette=30,31,32,33,34", func(s *Settings) bool { return s.NegativeColour == "\u001b[31m" }},
+		{"--palette=30,31,32,33,34,35", func(s *Settings) bool { return s.NegativeColour == "\u001b[35m" }},
 		// omitting "full" as this calls `TerminalSize()` which does not
 		// always work when being tested
 		//{"-s=full", func(s *Settings) bool { return s.Size == "full" }},
@@ -72,6 +82,39 @@ func TestNewSettings(t *testing.T) {
 		{"--tokenize=\\w", func(s *Settings) bool { return s.Tokenize == "\\w" }},
 		{"-m=\\d", func(s *Settings) bool { return s.MatchRegexp == "\\d" }},
 		{"--match=\\d", func(s *Settings) bool { return s.MatchRegexp == "\\d" }},
+		{"-o=svg", func(s *Settings) bool { return s.Output == "svg" }},
+		{"--output=png", func(s *Settings) bool { return s.Output == "png" }},
+		{"--sort=key", func(s *Settings) bool { return s.Sort == "key" }},
+		{"--sparkstats", func(s *Settings) bool { return s.SparkStats }},
+		{"--pairs=(\\w+) (\\d+)", func(s *Settings) bool { return s.PairRegexp == "(\\w+) (\\d+)" }},
+		{"--seriespalette=31,32", func(s *Settings) bool {
+			return s.ColourisedOutput && len(s.SeriesColours) == 2 && s.SeriesColours[1] == "\u001b[32m"
+		}},
+		{"--compare=yesterday.log", func(s *Settings) bool { return s.Compare == "yesterday.log" }},
+		{"--bucket=5m", func(s *Settings) bool { return s.Bucket == 5*time.Minute }},
+		{"--bins=fd", func(s *Settings) bool { return s.Bins == "fd" && s.Binned && s.Sort == "key" }},
+		{"--binwidth=0.5", func(s *Settings) bool { return s.BinWidth == 0.5 && s.Binned }},
+		{"--bounds=0,10,100", func(s *Settings) bool { return fmt.Sprint(s.Bounds) == "[0 10 100]" && s.Binned }},
+		{"--logbins", func(s *Settings) bool { return s.LogBins && s.Binned }},
+		{"--rate", func(s *Settings) bool { return s.Rate && s.NumOnly == "mon" }},
+		{"--maxrecord=16MiB", func(s *Settings) bool { return s.MaxRecordSize == 16*1024*1024 }},
+		{"--maxrecord=4096", func(s *Settings) bool { return s.MaxRecordSize == 4096 }},
+		{"--stage=split:white", func(s *Settings) bool { return fmt.Sprint(s.Stages) == "[split:white]" }},
+		{"--pattern=ticket=OPS-\\d+", func(s *Settings) bool { return s.Patterns["ticket"] == "OPS-\\d+" }},
+		{"--format=combined", func(s *Settings) bool { return s.Format == "combined" }},
+		{"--format=nginx:$remote_addr $status", func(s *Settings) bool { return s.Format == "nginx:$remote_addr $status" }},
+		{"--format=syslog", func(s *Settings) bool { return s.Format == "syslog" }},
+		{"--field=status", func(s *Settings) bool { return s.Field == "status" }},
+		{"--weight=bytes", func(s *Settings) bool { return s.Weight == "bytes" }},
+		{"--value=request_time", func(s *Settings) bool { return s.Weight == "request_time" }},
+		{"--distinct", func(s *Settings) bool { return s.Agg == "distinct" && s.Distinct == "" }},
+		{"--distinct=remote_addr", func(s *Settings) bool { return s.Agg == "distinct" && s.Distinct == "remote_addr" }},
+		{"--agg=p95", func(s *Settings) bool { return s.Agg == "p95" }},
+		{"--agg=AVG", func(s *Settings) bool { return s.Agg == "mean" }},
+		{"--separator=nul", func(s *Settings) bool { return s.Separator == "\x00" }},
+		{"--separator=\\t", func(s *Settings) bool { return s.Separator == "\t" }},
+		{"--separator=--", func(s *Settings) bool { return s.Separator == "--" }},
+		{"--separatorregexp=\\n\\s*\\n", func(s *Settings) bool { return s.SeparatorRegexp == "\\n\\s*\\n" }},
 		// the following test special values for certain keys
 		{"--keys=10", func(s *Settings) bool { return s.MaxKeys == s.Height+3000 }},
 		{"--char=ba", func(s *Settings) bool { return s.UnicodeMode && s.HistogramChar == "\u25ac" }},
@@ -91,7 +134,10 @@ func TestNewSettings(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.arg, func(t *testing.T) {
-			s := NewSettings(t.Name(), []string{RC_FILE, tc.arg})
+			s, err := NewSettings(t.Name(), []string{RC_FILE, tc.arg})
+			if err != nil {
+				t.Fatalf("NewSettings returned an error: %s", err)
+			}
 			if !tc.checker(s) {
 				t.Errorf("Option '%s' incorrectly handled", tc.arg)
 			}
@@ -99,9 +145,71 @@ func TestNewSettings(t *testing.T) {
 	}
 }
 
+func TestNewSettings_Errors(t *testing.T) {
+	testCases := []struct {
+		arg      string
+		expected string
+	}{
+		{"--width=wide", `invalid value "wide" for --width: must be a whole number`},
+		{"-h=-1", `invalid value "-1" for -h: must be a whole number`},
+		{"--keys=", `invalid value "" for --keys: must be a whole number`},
+		{"--char=", `invalid value "" for --char: needs at least one character`},
+		{"--numonly=", `invalid value "" for --numonly: must be abs or mon, or a synonym`},
+		{"--bucket=soon", `invalid value "soon" for --bucket: must be a duration such as 5m or 1h`},
+		{"--binwidth=x", `invalid value "x" for --binwidth: must be a number`},
+		{"--bounds=1,x", `invalid value "1,x" for --bounds: "x" is not a number`},
+		{"--maxrecord=big", `invalid value "big" for --maxrecord: must be a size such as 4096 or 16MiB`},
+		{"--pattern=ticket", `invalid value "ticket" for --pattern: needs a name and a regexp, as NAME=REGEXP`},
+		{"--pattern=ticket=(", "invalid value \"ticket=(\" for --pattern: error parsing regexp: missing closing ): `(`"},
+		{"--match=(", "invalid value \"(\" for --match: error parsing regexp: missing closing ): `(`"},
+		{"--palette=0,31", `invalid value "0,31" for --palette: needs five colours, as r,k,c,p,g[,n]`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.arg, func(t *testing.T) {
+			_, err := NewSettings(t.Name(), []string{RC_FILE, tc.arg})
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("NewSettings error incorrect: expected %v; actual %v", tc.expected, err)
+			}
+			if _, ok := err.(*FlagError); !ok {
+				t.Errorf("NewSettings error should be a *FlagError: %#v", err)
+			}
+		})
+	}
+}
+
+func TestNewSettings_AggConflicts(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--agg=mean", "--numonly"}, `invalid value "mean" for --agg: cannot be used with --numonly`},
+		{[]string{"--graph=multi", "--agg=max"}, `invalid value "max" for --agg: cannot be used with --graph=multi`},
+		{[]string{"--output=pdf"}, `invalid value "pdf" for --output: must be text, svg, png, md, html, vertical, spark, heatmap or stacked`},
+		{[]string{"--distinct", "--output=heatmap"}, `invalid value "distinct" for --agg: cannot be used with --output=heatmap`},
+		{[]string{"--agg=sum", "--output=stacked"}, `invalid value "sum" for --agg: cannot be used with --output=stacked`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			_, err := NewSettings(t.Name(), append([]string{RC_FILE}, tc.args...))
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("NewSettings error incorrect: expected %v; actual %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestNewSettings_Help(t *testing.T) {
+	s, err := NewSettings(t.Name(), []string{RC_FILE, "--help"})
+	if err != ErrHelp || s == nil {
+		t.Errorf("NewSettings should return the settings and ErrHelp for --help: returned %v, %v", s, err)
+	}
+}
+
 func TestDoUsage(t *testing.T) {
 	buf := new(bytes.Buffer)
-	s := NewSettings(t.Name(), []string{})
+	s, _ := NewSettings(t.Name(), []string{})
 
 	doUsage(s, buf)
 
@@ -109,3 +217,16 @@ func TestDoUsage(t *testing.T) {
 		t.Error("No usage printed")
 	}
 }
+
+func TestIsTerminal(t *testing.T) {
+	f, err := ioutil.TempFile("", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if IsTerminal(f) {
+		t.Error("IsTerminal should be false for a regular file")
+	}
+}
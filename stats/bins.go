@@ -0,0 +1,237 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxBins stops a small bin width over a wide range from producing more bins
+// than could ever be drawn
+const maxBins = 10000
+
+// Binning says how to divide a Sample into bins. Bounds, if given, are the
+// edges of the bins. Otherwise Width, if given, is the width of each bin.
+// Otherwise Count is the number of bins, or the rule for choosing it:
+// "sturges" or "fd" (Freedman–Diaconis). With Log the bins are spaced evenly
+// on a logarithmic scale, and Width is the ratio between successive edges.
+type Binning struct {
+	Count  string
+	Width  float64
+	Bounds []float64
+	Log    bool
+}
+
+// Edges returns the edges of the bins b divides sample into, in ascending
+// order
+func (sample Sample) Edges(b Binning) ([]float64, error) {
+	if len(b.Bounds) > 0 {
+		if len(b.Bounds) < 2 {
+			return nil, errors.New("bin bounds need at least two edges")
+		}
+		for i := 1; i < len(b.Bounds); i++ {
+			if b.Bounds[i] <= b.Bounds[i-1] {
+				return nil, errors.New("bin bounds must be in ascending order")
+			}
+		}
+		return b.Bounds, nil
+	}
+
+	o := sample.ordered()
+	if len(o.values) == 0 {
+		return nil, nil
+	}
+	min, max := o.values[0], o.values[len(o.values)-1]
+
+	// logarithmic bins are linear bins of the logarithms
+	width := b.Width
+	if b.Log {
+		logs := make(Sample)
+		for v, n := range sample {
+			if n == 0 {
+				continue
+			}
+			if v <= 0 {
+				return nil, errors.New("logarithmic bins need values greater than zero")
+			}
+			logs[math.Log(v)] += n
+		}
+		sample = logs
+		if width != 0 {
+			if width <= 1 {
+				return nil, errors.New("logarithmic bin width must be greater than one")
+			}
+			width = math.Log(width)
+		}
+	}
+
+	edges, err := sample.linearEdges(b.Count, width)
+	if err != nil {
+		return nil, err
+	}
+	if b.Log {
+		for i, e := range edges {
+			edges[i] = math.Exp(e)
+		}
+	}
+
+	// rounding, in the spacing or in Exp, can leave the outer edges just
+	// inside the smallest or largest observation, which Bin would then drop
+	last := len(edges) - 1
+	if width == 0 || edges[0] > min {
+		edges[0] = min
+	}
+	if (width == 0 && max > min) || edges[last] < max {
+		edges[last] = max
+	}
+	return edges, nil
+}
+
+// linearEdges returns the edges of equal-width bins covering sample
+func (sample Sample) linearEdges(count string, width float64) ([]float64, error) {
+	o := sample.ordered()
+	if len(o.values) == 0 {
+		return nil, nil
+	}
+	min, max := o.values[0], o.values[len(o.values)-1]
+
+	if width < 0 {
+		return nil, errors.New("bin width must be positive")
+	}
+	if width > 0 {
+		// align the edges to multiples of the width
+		start := math.Floor(min/width) * width
+		n := int(math.Floor((max-start)/width)) + 1
+		if n > maxBins {
+			return nil, fmt.Errorf("bin width %g makes more than %d bins", width, maxBins)
+		}
+		return spacedEdges(start, width, n), nil
+	}
+
+	n := 0
+	switch count {
+	case "", "sturges":
+		n = sturges(o.count())
+	case "fd":
+		iqr := o.percentile(75) - o.percentile(25)
+		if iqr == 0 {
+			n = sturges(o.count())
+		} else {
+			n = int(math.Ceil((max - min) / (2 * iqr / math.Cbrt(float64(o.count())))))
+		}
+	default:
+		var err error
+		n, err = strconv.Atoi(count)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("bins must be a positive number, sturges or fd: %s", count)
+		}
+	}
+	if n > maxBins {
+		n = maxBins
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	if max == min {
+		return []float64{min, min + 1}, nil
+	}
+	return spacedEdges(min, (max-min)/float64(n), n), nil
+}
+
+// sturges is Sturges' rule for the number of bins for n observations
+func sturges(n uint64) int {
+	return int(math.Ceil(math.Log2(float64(n)))) + 1
+}
+
+// spacedEdges returns the n+1 edges of n bins of width starting at start
+func spacedEdges(start float64, width float64, n int) []float64 {
+	edges := make([]float64, n+1)
+	for i := range edges {
+		edges[i] = start + float64(i)*width
+	}
+	return edges
+}
+
+// Bin counts the observations in each bin between successive edges. Every bin
+// is present, keyed by its range as "[lo, hi)", even if it is empty. The last
+// bin also holds observations equal to its upper edge, so its key is
+// "[lo, hi]". Observations outside the edges are not counted.
+func (sample Sample) Bin(edges []float64) map[string]float64 {
+	bins := make(map[string]float64)
+	if len(edges) < 2 {
+		return bins
+	}
+
+	names := formatEdges(edges)
+	keys := make([]string, len(edges)-1)
+	for i := range keys {
+		closing := ")"
+		if i == len(keys)-1 {
+			closing = "]"
+		}
+		keys[i] = fmt.Sprintf("[%s, %s%s", names[i], names[i+1], closing)
+		bins[keys[i]] = 0
+	}
+
+	for v, n := range sample {
+		i := sort.SearchFloat64s(edges, v)
+		if i == len(edges) || edges[i] != v {
+			i--
+		}
+		if i == len(keys) {
+			i--
+		}
+		if i >= 0 && v <= edges[len(edges)-1] {
+			bins[keys[i]] += float64(n)
+		}
+	}
+	return bins
+}
+
+// ParseBinKey returns the edges of the bin named by a key made by Bin, and
+// whether the bin includes its upper edge
+func ParseBinKey(key string) (lo float64, hi float64, closed bool, ok bool) {
+	if !strings.HasPrefix(key, "[") || !(strings.HasSuffix(key, ")") || strings.HasSuffix(key, "]")) {
+		return 0, 0, false, false
+	}
+	edges := strings.SplitN(key[1:len(key)-1], ", ", 2)
+	if len(edges) != 2 {
+		return 0, 0, false, false
+	}
+	lo, errLo := strconv.ParseFloat(edges[0], 64)
+	hi, errHi := strconv.ParseFloat(edges[1], 64)
+	if errLo != nil || errHi != nil {
+		return 0, 0, false, false
+	}
+	return lo, hi, strings.HasSuffix(key, "]"), true
+}
+
+// formatEdges writes the edges to six significant figures, or to as many more
+// as it takes for no two neighbouring edges to look the same
+func formatEdges(edges []float64) []string {
+	names := make([]string, len(edges))
+	for digits := 6; digits <= 17; digits++ {
+		distinct := true
+		for i, e := range edges {
+			names[i] = formatEdge(e, digits)
+			if i > 0 && names[i] == names[i-1] {
+				distinct = false
+			}
+		}
+		if distinct {
+			break
+		}
+	}
+	return names
+}
+
+// formatEdge writes a bin edge to the given number of significant figures,
+// without the exponent strconv would use for large numbers
+func formatEdge(v float64, digits int) string {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(v, 'g', digits, 64), 64)
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
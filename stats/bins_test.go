@@ -0,0 +1,154 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSample_Edges(t *testing.T) {
+	sample := Sample{1: 1, 3: 2, 8: 1, 10: 4}
+
+	testCases := []struct {
+		name     string
+		binning  Binning
+		expected []float64
+	}{
+		{"Count", Binning{Count: "3"}, []float64{1, 4, 7, 10}},
+		{"Sturges", Binning{Count: "sturges"}, []float64{1, 3.25, 5.5, 7.75, 10}},
+		{"Default is Sturges", Binning{}, []float64{1, 3.25, 5.5, 7.75, 10}},
+		{"Freedman-Diaconis", Binning{Count: "fd"}, []float64{1, 5.5, 10}},
+		{"Width", Binning{Width: 4}, []float64{0, 4, 8, 12}},
+		{"Bounds", Binning{Bounds: []float64{0, 5, 100}}, []float64{0, 5, 100}},
+		{"Log count", Binning{Count: "1", Log: true}, []float64{1, 10}},
+		{"Log width", Binning{Width: 10, Log: true}, []float64{1, 10, 100}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			edges, err := sample.Edges(tc.binning)
+			if err != nil {
+				t.Fatalf("Edges returned an error: %s", err)
+			}
+			if fmt.Sprintf("%.6g", edges) != fmt.Sprintf("%.6g", tc.expected) {
+				t.Errorf("Edges incorrect: expected %v; actual %v", tc.expected, edges)
+			}
+		})
+	}
+}
+
+func TestSample_EdgesErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		sample  Sample
+		binning Binning
+	}{
+		{"Bad count", Sample{1: 1}, Binning{Count: "many"}},
+		{"Unordered bounds", Sample{1: 1}, Binning{Bounds: []float64{5, 1}}},
+		{"Log of zero", Sample{0: 1, 1: 1}, Binning{Log: true}},
+		{"Log width too small", Sample{1: 1}, Binning{Width: 0.5, Log: true}},
+		{"Too many bins", Sample{0: 1, 1e9: 1}, Binning{Width: 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.sample.Edges(tc.binning); err == nil {
+				t.Error("Edges should have returned an error")
+			}
+		})
+	}
+}
+
+func TestSample_Bin(t *testing.T) {
+	sample := Sample{1: 1, 3: 2, 8: 1, 10: 4, 11: 9}
+	bins := sample.Bin([]float64{1, 4, 7, 10})
+
+	expected := map[string]float64{"[1, 4)": 3, "[4, 7)": 0, "[7, 10]": 5}
+	if fmt.Sprint(bins) != fmt.Sprint(expected) {
+		t.Errorf("Bin incorrect: expected %v; actual %v", expected, bins)
+	}
+}
+
+func TestSample_BinKeepsEveryObservation(t *testing.T) {
+	testCases := []struct {
+		name    string
+		sample  Sample
+		binning Binning
+	}{
+		{"Log count", Sample{1: 1, 2: 1, 5: 1, 7: 1, 1000: 1}, Binning{Count: "3", Log: true}},
+		{"Log width", Sample{0.3: 2, 3: 1, 30: 1}, Binning{Width: 10, Log: true}},
+		{"Count", Sample{0.1: 1, 0.2: 3, 0.7: 1}, Binning{Count: "7"}},
+		{"Width", Sample{0.1: 1, 0.3: 1, 0.7: 2}, Binning{Width: 0.1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			edges, err := tc.sample.Edges(tc.binning)
+			if err != nil {
+				t.Fatalf("Edges returned an error: %s", err)
+			}
+			expected, actual := 0.0, 0.0
+			for _, n := range tc.sample {
+				expected += float64(n)
+			}
+			for _, n := range tc.sample.Bin(edges) {
+				actual += n
+			}
+			if actual != expected {
+				t.Errorf("Bin counts incorrect: expected %v; actual %v (edges %v)", expected, actual, edges)
+			}
+		})
+	}
+}
+
+func TestSample_BinDistinctKeys(t *testing.T) {
+	sample := Sample{1000000: 1, 1000000.5: 1, 1000001: 1}
+	bins := sample.Bin([]float64{1000000, 1000000.25, 1000000.5, 1000000.75, 1000001})
+
+	expected := map[string]float64{
+		"[1000000, 1000000.2)":   1,
+		"[1000000.2, 1000000.5)": 0,
+		"[1000000.5, 1000000.8)": 1,
+		"[1000000.8, 1000001]":   1,
+	}
+	if fmt.Sprint(bins) != fmt.Sprint(expected) {
+		t.Errorf("Bin incorrect: expected %v; actual %v", expected, bins)
+	}
+}
+
+func TestParseBinKey(t *testing.T) {
+	testCases := []struct {
+		key    string
+		lo, hi float64
+		closed bool
+		ok     bool
+	}{
+		{"[1, 4)", 1, 4, false, true},
+		{"[-2.5, 1000000]", -2.5, 1000000, true, true},
+		{"7", 0, 0, false, false},
+		{"[a, b)", 0, 0, false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			lo, hi, closed, ok := ParseBinKey(tc.key)
+			if lo != tc.lo || hi != tc.hi || closed != tc.closed || ok != tc.ok {
+				t.Errorf("ParseBinKey incorrect: expected %v %v %v %v; actual %v %v %v %v",
+					tc.lo, tc.hi, tc.closed, tc.ok, lo, hi, closed, ok)
+			}
+		})
+	}
+}
+
+func TestFormatEdge(t *testing.T) {
+	testCases := map[float64]string{
+		0.30000000000000004: "0.3",
+		1000000:             "1000000",
+		2.0 / 3:             "0.666667",
+	}
+
+	for v, expected := range testCases {
+		if actual := formatEdge(v, 6); actual != expected {
+			t.Errorf("formatEdge incorrect: expected %s; actual %s", expected, actual)
+		}
+	}
+}
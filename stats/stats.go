@@ -0,0 +1,148 @@
+// Package stats summarises numeric input: the distribution of the numbers
+// themselves rather than how often each distinct string was seen.
+package stats
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Sample is a weighted set of numeric observations, mapping each distinct
+// value to the number of times it was observed
+type Sample map[float64]uint
+
+// FromKeys returns the sample of the numeric keys in tokenCounts, each
+// observed as many times as it was counted. Keys that are not numbers, or
+// were not counted a whole number of times, are left out.
+func FromKeys(tokenCounts map[string]float64) Sample {
+	sample := make(Sample)
+	for k, v := range tokenCounts {
+		if f, err := strconv.ParseFloat(k, 64); err == nil && v > 0 && v == math.Floor(v) {
+			sample[f] += uint(v)
+		}
+	}
+	return sample
+}
+
+// FromValues returns the sample of the values in tokenCounts, each observed
+// once, as for --numonly where the keys are just sequence numbers
+func FromValues(tokenCounts map[string]float64) Sample {
+	sample := make(Sample)
+	for _, v := range tokenCounts {
+		sample[float64(v)]++
+	}
+	return sample
+}
+
+// Summary describes a Sample
+type Summary struct {
+	Count  uint64
+	Min    float64
+	Max    float64
+	Mean   float64
+	Median float64
+	P90    float64
+	P95    float64
+	P99    float64
+	StdDev float64
+	Mode   float64
+}
+
+// Summarize computes the summary statistics of sample. The percentiles
+// interpolate linearly between the closest observations and the standard
+// deviation is that of the whole population. It returns nil for an empty
+// sample.
+func Summarize(sample Sample) *Summary {
+	o := sample.ordered()
+	if len(o.values) == 0 {
+		return nil
+	}
+
+	s := &Summary{
+		Count: o.count(),
+		Min:   o.values[0],
+		Max:   o.values[len(o.values)-1],
+		Mode:  o.values[0],
+	}
+
+	sum := 0.0
+	for _, v := range o.values {
+		sum += v * float64(sample[v])
+		if sample[v] > sample[s.Mode] {
+			s.Mode = v
+		}
+	}
+	s.Mean = sum / float64(s.Count)
+
+	squares := 0.0
+	for _, v := range o.values {
+		squares += (v - s.Mean) * (v - s.Mean) * float64(sample[v])
+	}
+	s.StdDev = math.Sqrt(squares / float64(s.Count))
+
+	s.Median = o.percentile(50)
+	s.P90 = o.percentile(90)
+	s.P95 = o.percentile(95)
+	s.P99 = o.percentile(99)
+
+	return s
+}
+
+// Percentile returns the p'th percentile of sample, interpolating linearly
+// between the closest observations, or NaN for an empty sample
+func (sample Sample) Percentile(p float64) float64 {
+	o := sample.ordered()
+	if len(o.values) == 0 {
+		return math.NaN()
+	}
+	return o.percentile(p)
+}
+
+// ordered is a Sample sorted for looking up observations by rank
+type ordered struct {
+	values []float64
+	// cumulative[i] is the number of observations up to and including
+	// values[i]
+	cumulative []uint64
+}
+
+func (sample Sample) ordered() ordered {
+	o := ordered{}
+	for v, n := range sample {
+		if n > 0 {
+			o.values = append(o.values, v)
+		}
+	}
+	sort.Float64s(o.values)
+
+	total := uint64(0)
+	for _, v := range o.values {
+		total += uint64(sample[v])
+		o.cumulative = append(o.cumulative, total)
+	}
+	return o
+}
+
+// count returns the number of observations
+func (o ordered) count() uint64 {
+	if len(o.cumulative) == 0 {
+		return 0
+	}
+	return o.cumulative[len(o.cumulative)-1]
+}
+
+// percentile returns the p'th percentile, interpolating linearly between the
+// closest observations
+func (o ordered) percentile(p float64) float64 {
+	rank := p / 100 * float64(o.count()-1)
+	lower := o.valueAt(uint64(rank))
+	upper := o.valueAt(uint64(math.Ceil(rank)))
+	return lower + (rank-math.Floor(rank))*(upper-lower)
+}
+
+// valueAt returns the i'th smallest observation (counting from zero)
+func (o ordered) valueAt(i uint64) float64 {
+	j := sort.Search(len(o.cumulative), func(j int) bool { return o.cumulative[j] > i })
+	return o.values[j]
+}
@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFromKeys(t *testing.T) {
+	sample := FromKeys(map[string]float64{"1": 2, "2.5": 1, "word": 7})
+	if len(sample) != 2 || sample[1] != 2 || sample[2.5] != 1 {
+		t.Errorf("FromKeys incorrect: %v", sample)
+	}
+}
+
+func TestFromValues(t *testing.T) {
+	sample := FromValues(map[string]float64{"1": 5, "2": 5, "3": 7})
+	if len(sample) != 2 || sample[5] != 2 || sample[7] != 1 {
+		t.Errorf("FromValues incorrect: %v", sample)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	if Summarize(Sample{}) != nil {
+		t.Error("Summarize of an empty sample should be nil")
+	}
+
+	testCases := []struct {
+		name     string
+		sample   Sample
+		expected Summary
+	}{
+		{
+			name:     "Single value",
+			sample:   Sample{3: 1},
+			expected: Summary{Count: 1, Min: 3, Max: 3, Mean: 3, Median: 3, P90: 3, P95: 3, P99: 3, StdDev: 0, Mode: 3},
+		},
+		{
+			name:     "Even count",
+			sample:   Sample{1: 1, 2: 1, 3: 1, 4: 1},
+			expected: Summary{Count: 4, Min: 1, Max: 4, Mean: 2.5, Median: 2.5, P90: 3.7, P95: 3.85, P99: 3.97, StdDev: math.Sqrt(1.25), Mode: 1},
+		},
+		{
+			name:     "Weighted",
+			sample:   Sample{10: 1, 20: 3, 30: 1},
+			expected: Summary{Count: 5, Min: 10, Max: 30, Mean: 20, Median: 20, P90: 26, P95: 28, P99: 29.6, StdDev: math.Sqrt(40), Mode: 20},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := Summarize(tc.sample)
+			actual := []float64{float64(s.Count), s.Min, s.Max, s.Mean, s.Median, s.P90, s.P95, s.P99, s.StdDev, s.Mode}
+			e := tc.expected
+			expected := []float64{float64(e.Count), e.Min, e.Max, e.Mean, e.Median, e.P90, e.P95, e.P99, e.StdDev, e.Mode}
+			for i := range expected {
+				if math.Abs(actual[i]-expected[i]) > 1e-9 {
+					t.Errorf("Summarize incorrect: expected %+v; actual %+v", e, *s)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSample_Percentile(t *testing.T) {
+	if !math.IsNaN(Sample{}.Percentile(50)) {
+		t.Error("Percentile of an empty sample should be NaN")
+	}
+	if p := (Sample{10: 1, 20: 3, 30: 1}).Percentile(90); math.Abs(p-26) > 1e-9 {
+		t.Errorf("Percentile incorrect: expected %v; actual %v", 26, p)
+	}
+}
@@ -0,0 +1,192 @@
+package tokenize
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// accessLogFormats are the access log formats known by name, as nginx
+// log_format strings
+var accessLogFormats = map[string]string{
+	"common":   `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent`,
+	"combined": `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`,
+}
+
+// accessLogVariables are the log_format variables each field is read from,
+// most preferred first. Any other field is read from the variable of the same
+// name.
+var accessLogVariables = map[string][]string{
+	"time":       {"time_local", "time_iso8601"},
+	"method":     {"request_method"},
+	"path":       {"uri"},
+	"bytes":      {"body_bytes_sent", "bytes_sent"},
+	"referrer":   {"http_referer"},
+	"user_agent": {"http_user_agent"},
+}
+
+// requestParts are the fields that can instead be taken from the request line,
+// by their position in it
+var requestParts = map[string]int{
+	"method":   0,
+	"path":     1,
+	"protocol": 2,
+}
+
+var logFormatVariable = regexp.MustCompile(`\$(?:\{(\w+)\}|(\w+))`)
+
+// LogFields names the fields of a log line that a Tokenizer for a log format
+// counts
+type LogFields struct {
+	// Key is the field whose values are counted
+	Key string
+	// Value, if set, is a numeric field that each line counts by, rather
+	// than once
+	Value string
+	// Distinct, if set, is the field whose distinct values are counted for
+	// each key, with a DistinctCounter unless given WithCounter
+	Distinct string
+}
+
+// options returns opts, after a DistinctCounter if fields has a Distinct
+// field, so that opts can still choose another Counter
+func (fields LogFields) options(opts []Option) []Option {
+	if fields.Distinct == "" {
+		return opts
+	}
+	return append([]Option{WithCounter(func() Counter { return NewDistinctCounter() })}, opts...)
+}
+
+// NewAccessLogTokenizer returns a Tokenizer for web server access logs that
+// counts the values of a field, eg remote_addr, method, path (the default,
+// which leaves out any query string), status, bytes, referrer or user_agent,
+// that match matcher. logFormat is "common" or "combined", or an nginx
+// log_format string. A Value field might be bytes or request_time.
+func NewAccessLogTokenizer(logFormat string, fields LogFields, matcher string, opts ...Option) (Tokenizer, error) {
+	if named, ok := accessLogFormats[logFormat]; ok {
+		logFormat = named
+	}
+	if fields.Key == "" {
+		fields.Key = "path"
+	}
+
+	re, err := logFormatRegexp(logFormat)
+	if err != nil {
+		return nil, err
+	}
+	key, err := accessLogField(re, fields.Key)
+	if err != nil {
+		return nil, err
+	}
+	keyMatcher, err := compileMatcher(matcher)
+	if err != nil {
+		return nil, err
+	}
+	var value, distinct func([]string) string
+	if fields.Value != "" {
+		if value, err = accessLogField(re, fields.Value); err != nil {
+			return nil, err
+		}
+	}
+	if fields.Distinct != "" {
+		if distinct, err = accessLogField(re, fields.Distinct); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewPipeline(fields.options(opts)...).Extract(func(line string) (Token, bool) {
+		res := re.FindStringSubmatch(line)
+		if res == nil {
+			return Token{}, false
+		}
+		t := Token{Key: key(res), Value: 1}
+		if distinct != nil {
+			t.Secondary = distinct(res)
+		}
+		if value != nil {
+			// nginx and Apache log no bytes sent as "-"
+			if v := value(res); v == "-" {
+				t.Value = 0
+			} else if weighted, err := strconv.ParseFloat(v, 64); err == nil {
+				t.Value = weighted
+			} else {
+				return Token{}, false
+			}
+		}
+		return t, true
+	}).Filter(MatchKey(keyMatcher)), nil
+}
+
+// logFormatRegexp returns a regexp matching lines written in the nginx
+// log_format, with a group named for each variable
+func logFormatRegexp(logFormat string) (*regexp.Regexp, error) {
+	var buf bytes.Buffer
+	buf.WriteString("^")
+	last := 0
+	for _, loc := range logFormatVariable.FindAllStringSubmatchIndex(logFormat, -1) {
+		buf.WriteString(regexp.QuoteMeta(logFormat[last:loc[0]]))
+		// $name, or ${name}
+		start, end := loc[4], loc[5]
+		if start < 0 {
+			start, end = loc[2], loc[3]
+		}
+		name := logFormat[start:end]
+		fmt.Fprintf(&buf, `(?P<%s>.*?)`, name)
+		last = loc[1]
+	}
+	buf.WriteString(regexp.QuoteMeta(logFormat[last:]))
+	buf.WriteString("$")
+
+	return regexp.Compile(buf.String())
+}
+
+// accessLogField returns a function that gets field from the submatches of re
+func accessLogField(re *regexp.Regexp, field string) (func([]string) string, error) {
+	index := func(name string) int {
+		for i, n := range re.SubexpNames() {
+			if n == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for _, name := range append(accessLogVariables[field], field) {
+		if i := index(name); i > 0 {
+			if field == "path" {
+				return func(res []string) string { return withoutQuery(res[i]) }, nil
+			}
+			return func(res []string) string { return res[i] }, nil
+		}
+	}
+
+	if i := index("request_uri"); i > 0 && field == "path" {
+		return func(res []string) string { return withoutQuery(res[i]) }, nil
+	}
+	if part, ok := requestParts[field]; ok {
+		if i := index("request"); i > 0 {
+			return func(res []string) string {
+				parts := strings.Fields(res[i])
+				if part >= len(parts) {
+					return ""
+				}
+				if field == "path" {
+					return withoutQuery(parts[part])
+				}
+				return parts[part]
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("log format has no %s field", field)
+}
+
+// withoutQuery strips the query string from a request path
+func withoutQuery(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
@@ -0,0 +1,77 @@
+package tokenize
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+const combinedLog = `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?x=1 HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"
+10.0.0.2 - - [10/Oct/2000:13:55:37 -0700] "POST /login HTTP/1.1" 302 - "-" "curl/7.1"
+not an access log line
+10.0.0.2 - - [10/Oct/2000:13:55:38 -0700] "GET /apache_pb.gif HTTP/1.1" 304 0 "-" "curl/7.1"
+`
+
+func TestAccessLogTokenizer_Tokenize(t *testing.T) {
+	nginx := `$remote_addr [$time_local] "$request" $status ${request_time}s`
+
+	testCases := []struct {
+		name     string
+		format   string
+		field    string
+		weight   string
+		matcher  string
+		input    string
+		expected map[string]float64
+	}{
+		{"Path by default", "combined", "", "", ".", combinedLog, map[string]float64{"/apache_pb.gif": 2, "/login": 1}},
+		{"Method", "combined", "method", "", ".", combinedLog, map[string]float64{"GET": 2, "POST": 1}},
+		{"Status", "common", "status", "", "^3", combinedLog, map[string]float64{"302": 1, "304": 1}},
+		{"Remote address by bytes", "combined", "remote_addr", "bytes", ".", combinedLog, map[string]float64{"127.0.0.1": 2326, "10.0.0.2": 0}},
+		{"User agent", "combined", "user_agent", "", ".", combinedLog, map[string]float64{"Mozilla/4.08 [en] (Win98; I ;Nav)": 1, "curl/7.1": 2}},
+		{"Referrer", "combined", "referrer", "", "^http", combinedLog, map[string]float64{"http://www.example.com/start.html": 1}},
+		{"nginx log_format by request time", nginx, "path", "request_time", ".",
+			"1.2.3.4 [01/Jun/2017:12:00:00 +0000] \"GET /a?b HTTP/1.1\" 200 0.250s\n1.2.3.4 [01/Jun/2017:12:00:01 +0000] \"GET /a HTTP/1.1\" 200 1.5s\n",
+			map[string]float64{"/a": 1.75}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := NewAccessLogTokenizer(tc.format, LogFields{Key: tc.field, Value: tc.weight}, tc.matcher)
+			if err != nil {
+				t.Fatalf("NewAccessLogTokenizer returned an error: %s", err)
+			}
+			actual, err := a.Tokenize(bytes.NewBufferString(tc.input))
+			if err != nil {
+				t.Fatalf("Tokenize returned an error: %s", err)
+			}
+			if fmt.Sprint(actual) != fmt.Sprint(tc.expected) {
+				t.Errorf("Tokenize incorrect: expected %v; actual %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAccessLogTokenizer_Distinct(t *testing.T) {
+	a, err := NewAccessLogTokenizer("combined", LogFields{Key: "path", Distinct: "remote_addr"}, ".")
+	if err != nil {
+		t.Fatalf("NewAccessLogTokenizer returned an error: %s", err)
+	}
+	actual, _ := a.Tokenize(bytes.NewBufferString(combinedLog))
+	expected := map[string]float64{"/apache_pb.gif": 2, "/login": 1}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Tokenize incorrect: expected %v; actual %v", expected, actual)
+	}
+}
+
+func TestNewAccessLogTokenizer_UnknownField(t *testing.T) {
+	if _, err := NewAccessLogTokenizer("common", LogFields{Key: "user_agent"}, "."); err == nil {
+		t.Error("NewAccessLogTokenizer should reject a field the format does not have")
+	}
+	if _, err := NewAccessLogTokenizer("combined", LogFields{Value: "request_time"}, "."); err == nil {
+		t.Error("NewAccessLogTokenizer should reject a weight the format does not have")
+	}
+	if _, err := NewAccessLogTokenizer("common", LogFields{Distinct: "referrer"}, "."); err == nil {
+		t.Error("NewAccessLogTokenizer should reject a distinct field the format does not have")
+	}
+}
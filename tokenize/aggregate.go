@@ -0,0 +1,127 @@
+package tokenize
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bradfordboyle/go-distribution/stats"
+)
+
+// WithCounter has a Tokenizer built on a Pipeline tally with Counters made by
+// newCounter, eg from NewAggregateCounter
+func WithCounter(newCounter func() Counter) Option {
+	return func(o *options) {
+		o.newCounter = newCounter
+	}
+}
+
+// NewAggregateCounter returns a constructor for Counters that tally the values
+// of each key by agg:
+//
+//	sum       the total
+//	mean      the average
+//	min       the smallest value
+//	max       the largest value
+//	count     the number of values
+//	last      the last value
+//	distinct  the number of distinct secondary keys, see DistinctCounter
+//	pN        the N'th percentile, eg p95 or p99.9 (median is p50)
+func NewAggregateCounter(agg string) (func() Counter, error) {
+	switch agg {
+	case "sum":
+		return NewSumCounter, nil
+	case "mean":
+		return func() Counter { return meanCounter{sumCounter{}, map[string]float64{}} }, nil
+	case "min":
+		return func() Counter { return extremeCounter{sumCounter{}, false} }, nil
+	case "max":
+		return func() Counter { return extremeCounter{sumCounter{}, true} }, nil
+	case "count":
+		return func() Counter { return countCounter{sumCounter{}} }, nil
+	case "last":
+		return NewLastCounter, nil
+	case "distinct":
+		return func() Counter { return NewDistinctCounter() }, nil
+	}
+
+	if agg == "median" {
+		agg = "p50"
+	}
+	if strings.HasPrefix(agg, "p") {
+		if p, err := strconv.ParseFloat(agg[1:], 64); err == nil && p >= 0 && p <= 100 {
+			return func() Counter { return percentileCounter{map[string]stats.Sample{}, p} }, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown aggregate %q", agg)
+}
+
+type meanCounter struct {
+	sums   sumCounter
+	counts map[string]float64
+}
+
+func (c meanCounter) Add(t Token) {
+	c.sums.Add(t)
+	c.counts[t.Key]++
+}
+
+func (c meanCounter) Counts() map[string]float64 {
+	means := make(map[string]float64, len(c.sums))
+	for k, sum := range c.sums {
+		means[k] = sum / c.counts[k]
+	}
+	return means
+}
+
+func (c meanCounter) Len() int {
+	return len(c.sums)
+}
+
+// extremeCounter keeps the largest value for each key if max is set, otherwise
+// the smallest
+type extremeCounter struct {
+	sumCounter
+	max bool
+}
+
+func (c extremeCounter) Add(t Token) {
+	if v, ok := c.sumCounter[t.Key]; !ok || (c.max && t.Value > v) || (!c.max && t.Value < v) {
+		c.sumCounter[t.Key] = t.Value
+	}
+}
+
+type countCounter struct {
+	sumCounter
+}
+
+func (c countCounter) Add(t Token) {
+	c.sumCounter[t.Key]++
+}
+
+type percentileCounter struct {
+	samples map[string]stats.Sample
+	p       float64
+}
+
+func (c percentileCounter) Add(t Token) {
+	sample, ok := c.samples[t.Key]
+	if !ok {
+		sample = stats.Sample{}
+		c.samples[t.Key] = sample
+	}
+	sample[t.Value]++
+}
+
+func (c percentileCounter) Counts() map[string]float64 {
+	percentiles := make(map[string]float64, len(c.samples))
+	for k, sample := range c.samples {
+		percentiles[k] = sample.Percentile(c.p)
+	}
+	return percentiles
+}
+
+func (c percentileCounter) Len() int {
+	return len(c.samples)
+}
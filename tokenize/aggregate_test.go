@@ -0,0 +1,61 @@
+package tokenize
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestNewAggregateCounter(t *testing.T) {
+	tokens := []Token{{Key: "a", Value: 4}, {Key: "b", Value: 10}, {Key: "a", Value: 1}, {Key: "a", Value: 7}, {Key: "b", Value: 2}}
+
+	testCases := []struct {
+		agg      string
+		expected map[string]float64
+	}{
+		{"sum", map[string]float64{"a": 12, "b": 12}},
+		{"mean", map[string]float64{"a": 4, "b": 6}},
+		{"min", map[string]float64{"a": 1, "b": 2}},
+		{"max", map[string]float64{"a": 7, "b": 10}},
+		{"count", map[string]float64{"a": 3, "b": 2}},
+		{"last", map[string]float64{"a": 7, "b": 2}},
+		{"median", map[string]float64{"a": 4, "b": 6}},
+		{"p75", map[string]float64{"a": 5.5, "b": 8}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.agg, func(t *testing.T) {
+			newCounter, err := NewAggregateCounter(tc.agg)
+			if err != nil {
+				t.Fatalf("NewAggregateCounter returned an error: %s", err)
+			}
+			c := newCounter()
+			for _, token := range tokens {
+				c.Add(token)
+			}
+			if c.Len() != 2 {
+				t.Errorf("Len incorrect: expected 2; actual %d", c.Len())
+			}
+			if fmt.Sprint(c.Counts()) != fmt.Sprint(tc.expected) {
+				t.Errorf("Counts incorrect: expected %v; actual %v", tc.expected, c.Counts())
+			}
+		})
+	}
+
+	for _, agg := range []string{"avg", "p", "p101", "total"} {
+		if _, err := NewAggregateCounter(agg); err == nil {
+			t.Errorf("NewAggregateCounter should reject %q", agg)
+		}
+	}
+}
+
+func TestKeyValueTokenizer_WithCounter(t *testing.T) {
+	newCounter, _ := NewAggregateCounter("sum")
+	kv := NewKeyValueTokenizer(WithCounter(newCounter))
+
+	actual, _ := kv.Tokenize(bytes.NewBufferString("host1 100\nhost2 5\nhost1 20\n"))
+	expected := map[string]float64{"host1": 120, "host2": 5}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Tokenize incorrect: expected %v; actual %v", expected, actual)
+	}
+}
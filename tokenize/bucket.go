@@ -0,0 +1,133 @@
+package tokenize
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// maxBucketRange stops BucketRange from filling in more buckets than could
+// ever be drawn
+const maxBucketRange = 10000
+
+// timeLayouts are the timestamp formats BucketTime recognises, most specific
+// first
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"02/Jan/2006:15:04:05 -0700",
+	time.Stamp,
+	"2006-01-02",
+	"15:04:05",
+	"15:04",
+}
+
+// BucketTime rounds the timestamp s down to a multiple of bucket and returns
+// it in the format it was given in, so that "12:34:56" falls in the "12:34:00"
+// bucket for one minute. A timestamp with a time zone is rounded in that zone,
+// so that hours start on the hour there. Unix timestamps in seconds are also
+// recognised. Anything else is returned unchanged.
+func BucketTime(s string, bucket time.Duration) string {
+	if t, layout, ok := parseTime(s); ok {
+		return truncateLocal(t, bucket).Format(layout)
+	}
+
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		t := time.Unix(secs, 0).Truncate(bucket)
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+
+	return s
+}
+
+// BucketRange returns the buckets from the earliest of keys to the latest, in
+// order, with every bucket between them filled in, so that a chart of them
+// shows when nothing happened. keys must be timestamps in one format, rounded
+// by BucketTime; if not, or if the range holds too many buckets, they are
+// returned sorted, as they are.
+func BucketRange(keys []string, bucket time.Duration) []string {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	if len(keys) == 0 || bucket <= 0 {
+		return sorted
+	}
+
+	first, layout, ok := parseBucket(keys[0])
+	if !ok {
+		return sorted
+	}
+	last := first
+	for _, k := range keys[1:] {
+		t, l, ok := parseBucket(k)
+		if !ok || l != layout {
+			return sorted
+		}
+		if t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+
+	n := int(last.Sub(first)/bucket) + 1
+	if n > maxBucketRange {
+		return sorted
+	}
+	buckets := make([]string, n)
+	for i := range buckets {
+		t := first.Add(time.Duration(i) * bucket)
+		if layout == "" {
+			buckets[i] = strconv.FormatInt(t.Unix(), 10)
+		} else {
+			buckets[i] = t.Format(layout)
+		}
+	}
+	return buckets
+}
+
+// parseBucket parses a key made by BucketTime, returning the layout it is in,
+// or "" for a Unix timestamp
+func parseBucket(s string) (time.Time, string, bool) {
+	if t, layout, ok := parseTime(s); ok {
+		return t, layout, true
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), "", true
+	}
+	return time.Time{}, "", false
+}
+
+// truncateLocal rounds t down to a multiple of d in t's own time zone, rather
+// than in UTC as Time.Truncate does
+func truncateLocal(t time.Time, d time.Duration) time.Time {
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(d).Add(-shift)
+}
+
+// parseTime parses s in the first of timeLayouts it matches, and returns that
+// layout
+func parseTime(s string) (time.Time, string, bool) {
+	for _, layout := range timeLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, layout, true
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// parseTimestamp parses s in any of timeLayouts or as a Unix timestamp in
+// seconds, which may be fractional as from `date +%s.%N`
+func parseTimestamp(s string) (time.Time, bool) {
+	if t, _, ok := parseTime(s); ok {
+		return t, true
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(secs*1e9)), true
+	}
+	return time.Time{}, false
+}
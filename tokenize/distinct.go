@@ -0,0 +1,95 @@
+package tokenize
+
+import (
+	"math"
+)
+
+// DistinctThreshold is how many distinct secondary keys a DistinctCounter
+// counts exactly for a key before it estimates them
+const DistinctThreshold = 1000
+
+// DistinctRelativeError is the standard error, relative to the count, of the
+// counts a DistinctCounter estimates: 1.04/sqrt(2^hllPrecision)
+const DistinctRelativeError = 1.04 / 128
+
+// DistinctCounter is a Counter of how many distinct secondary keys are seen
+// with each key. Up to DistinctThreshold of them are counted exactly, and
+// beyond that a HyperLogLog sketch estimates them.
+type DistinctCounter struct {
+	exact    map[string]map[string]struct{}
+	sketches map[string]*hyperLogLog
+}
+
+func NewDistinctCounter() *DistinctCounter {
+	return &DistinctCounter{
+		exact:    make(map[string]map[string]struct{}),
+		sketches: make(map[string]*hyperLogLog),
+	}
+}
+
+func (c *DistinctCounter) Add(t Token) {
+	if sketch, ok := c.sketches[t.Key]; ok {
+		sketch.add(t.Secondary)
+		return
+	}
+
+	seen, ok := c.exact[t.Key]
+	if !ok {
+		seen = make(map[string]struct{})
+		c.exact[t.Key] = seen
+	}
+	seen[t.Secondary] = struct{}{}
+
+	if len(seen) > DistinctThreshold {
+		sketch := newHyperLogLog()
+		for secondary := range seen {
+			sketch.add(secondary)
+		}
+		c.sketches[t.Key] = sketch
+		delete(c.exact, t.Key)
+	}
+}
+
+func (c *DistinctCounter) Counts() map[string]float64 {
+	counts := make(map[string]float64, c.Len())
+	for k, seen := range c.exact {
+		counts[k] = float64(len(seen))
+	}
+	for k, sketch := range c.sketches {
+		counts[k] = math.Floor(sketch.estimate() + 0.5)
+	}
+	return counts
+}
+
+func (c *DistinctCounter) Len() int {
+	return len(c.exact) + len(c.sketches)
+}
+
+// Estimated reports whether any of the counts is an estimate
+func (c *DistinctCounter) Estimated() bool {
+	return len(c.sketches) > 0
+}
+
+// NewDistinctTokenizer returns a Tokenizer that counts the distinct secondary
+// keys seen with each primary key. The extractor regexp captures the primary
+// key in its first group and the secondary key in its second, as for
+// NewPairTokenizer. Primary keys must satisfy matcher.
+func NewDistinctTokenizer(extractor string, matcher string, opts ...Option) (Tokenizer, error) {
+	re, err := compilePairExtractor(extractor)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := compileMatcher(matcher)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]Option{WithCounter(func() Counter { return NewDistinctCounter() })}, opts...)
+	return NewPipeline(opts...).Extract(func(line string) (Token, bool) {
+		res := re.FindStringSubmatch(line)
+		if len(res) < 3 {
+			return Token{}, false
+		}
+		return Token{Key: res[1], Value: 1, Secondary: res[2]}, true
+	}).Filter(MatchKey(keys)), nil
+}
@@ -0,0 +1,45 @@
+package tokenize
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestDistinctCounter(t *testing.T) {
+	c := NewDistinctCounter()
+	for i := 0; i < 50000; i++ {
+		c.Add(Token{Key: "many", Value: 1, Secondary: strconv.Itoa(i)})
+		c.Add(Token{Key: "few", Value: 1, Secondary: strconv.Itoa(i % 10)})
+	}
+	c.Add(Token{Key: "edge", Value: 1, Secondary: "x"})
+
+	counts := c.Counts()
+	if c.Len() != 3 || counts["few"] != 10 || counts["edge"] != 1 {
+		t.Errorf("DistinctCounter should count few distinct values exactly: %v", counts)
+	}
+	if !c.Estimated() {
+		t.Error("DistinctCounter should estimate beyond the threshold")
+	}
+	// well within four standard errors
+	if e := math.Abs(counts["many"]-50000) / 50000; e > 4*DistinctRelativeError {
+		t.Errorf("DistinctCounter estimate too far out: %v is %.2f%% from 50000", counts["many"], e*100)
+	}
+}
+
+func TestDistinctTokenizer_Tokenize(t *testing.T) {
+	d, err := NewDistinctTokenizer("", "^/")
+	if err != nil {
+		t.Fatalf("NewDistinctTokenizer returned an error: %s", err)
+	}
+	actual, err := d.Tokenize(bytes.NewBufferString("/a ann\n/a bob\n/a ann\n/b ann\nc ann\n"))
+	if err != nil {
+		t.Fatalf("Tokenize returned an error: %s", err)
+	}
+	expected := map[string]float64{"/a": 2, "/b": 1}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Tokenize incorrect: expected %v; actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,68 @@
+package tokenize
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// hllPrecision is the number of bits of each hash that pick a register, so
+// there are 2^14 registers and a sketch takes 16kB
+const hllPrecision = 14
+
+// hyperLogLog is a HyperLogLog sketch, which estimates how many distinct
+// strings have been added to it in a fixed amount of memory
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{registers: make([]uint8, 1<<hllPrecision)}
+}
+
+func (h *hyperLogLog) add(s string) {
+	x := hash64(s)
+	i := x >> (64 - hllPrecision)
+	// the rank is the position of the first 1 bit after the register bits,
+	// counting from 1
+	rank := uint8(1)
+	for w := x << hllPrecision; w&(1<<63) == 0 && rank <= 64-hllPrecision; w <<= 1 {
+		rank++
+	}
+	if rank > h.registers[i] {
+		h.registers[i] = rank
+	}
+}
+
+// estimate returns the estimated number of distinct strings added, counting
+// the empty registers instead while few of them are filled
+func (h *hyperLogLog) estimate() float64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return e
+}
+
+// hash64 hashes s with FNV-1a, then mixes the bits as MurmurHash3 finishes,
+// since HyperLogLog needs every bit of the hash to be evenly distributed
+func hash64(s string) uint64 {
+	f := fnv.New64a()
+	f.Write([]byte(s))
+	x := f.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,128 @@
+package tokenize
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PairCounts tallies how often each secondary key is seen alongside each
+// primary key, e.g. endpoint by minute or endpoint by status code
+type PairCounts struct {
+	// Counts maps primary key to secondary key to count
+	Counts map[string]map[string]float64
+	// Secondaries holds every secondary key in the order first seen
+	Secondaries []string
+	seen        map[string]bool
+}
+
+func NewPairCounts() *PairCounts {
+	return &PairCounts{
+		Counts: make(map[string]map[string]float64),
+		seen:   make(map[string]bool),
+	}
+}
+
+// Add adds n to the count for the (primary, secondary) pair
+func (pc *PairCounts) Add(primary string, secondary string, n float64) {
+	row, ok := pc.Counts[primary]
+	if !ok {
+		row = make(map[string]float64)
+		pc.Counts[primary] = row
+	}
+	if !pc.seen[secondary] {
+		pc.seen[secondary] = true
+		pc.Secondaries = append(pc.Secondaries, secondary)
+	}
+	row[secondary] += n
+}
+
+// Totals returns the count for each primary key summed over every secondary
+// key, as a plain Tokenizer would have counted it
+func (pc *PairCounts) Totals() map[string]float64 {
+	totals := make(map[string]float64)
+	for primary, row := range pc.Counts {
+		for _, v := range row {
+			totals[primary] += v
+		}
+	}
+	return totals
+}
+
+// PairTokenizer counts pairs of keys in its input, returning what it had
+// counted along with ErrInterrupted if it is interrupted, as a Tokenizer does
+type PairTokenizer interface {
+	TokenizePairs(io.Reader) (*PairCounts, error)
+}
+
+type pairTokenizer struct {
+	options
+	extractor *regexp.Regexp
+	matcher   *regexp.Regexp
+	bucket    time.Duration
+}
+
+// PAIR_REGEX takes the first whitespace-separated field of a line as the
+// primary key and the rest of the line as the secondary key
+const PAIR_REGEX = `^\s*(\S+)\s+(.*\S)\s*$`
+
+// NewPairTokenizer returns a PairTokenizer whose extractor regexp captures the
+// primary key in its first group and the secondary key in its second. Primary
+// keys must satisfy matcher. A non-zero bucket rounds secondary keys that are
+// timestamps down to a multiple of that duration.
+func NewPairTokenizer(extractor string, matcher string, bucket time.Duration, opts ...Option) (PairTokenizer, error) {
+	re, err := compilePairExtractor(extractor)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := compileMatcher(matcher)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pairTokenizer{
+		options:   newOptions(opts),
+		extractor: re,
+		matcher:   keys,
+		bucket:    bucket,
+	}, nil
+}
+
+// compilePairExtractor compiles the --pairs regexp, or PAIR_REGEX if it is
+// not set
+func compilePairExtractor(extractor string) (*regexp.Regexp, error) {
+	if extractor == "" {
+		extractor = PAIR_REGEX
+	}
+	re, err := regexp.Compile(extractor)
+	if err != nil {
+		return nil, fmt.Errorf("pair regexp: %s", err)
+	}
+	return re, nil
+}
+
+func (p *pairTokenizer) TokenizePairs(reader io.Reader) (*PairCounts, error) {
+	pairCounts := NewPairCounts()
+
+	err := p.scan(reader, func() int { return len(pairCounts.Counts) }, func(line string) (int, int, error) {
+		line = strings.TrimRight(line, "\n")
+		res := p.extractor.FindStringSubmatch(line)
+		if len(res) < 3 || !p.matcher.MatchString(res[1]) {
+			return 1, 0, nil
+		}
+
+		secondary := res[2]
+		if p.bucket > 0 {
+			secondary = BucketTime(secondary, p.bucket)
+		}
+		pairCounts.Add(res[1], secondary, 1)
+		return 1, 1, nil
+	})
+	if err != nil && err != ErrInterrupted {
+		return nil, err
+	}
+
+	return pairCounts, err
+}
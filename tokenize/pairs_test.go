@@ -0,0 +1,124 @@
+package tokenize
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPairCounts_Add(t *testing.T) {
+	pc := NewPairCounts()
+	pc.Add("a", "y", 1)
+	pc.Add("a", "x", 2)
+	pc.Add("b", "y", 3)
+	pc.Add("a", "y", 1)
+
+	if pc.Counts["a"]["y"] != 2 || pc.Counts["a"]["x"] != 2 || pc.Counts["b"]["y"] != 3 {
+		t.Errorf("PairCounts.Add() counted incorrectly: %v", pc.Counts)
+	}
+	if len(pc.Secondaries) != 2 || pc.Secondaries[0] != "y" || pc.Secondaries[1] != "x" {
+		t.Errorf("PairCounts.Secondaries incorrect: expected [y x]; actual %v", pc.Secondaries)
+	}
+
+	totals := pc.Totals()
+	if totals["a"] != 4 || totals["b"] != 3 {
+		t.Errorf("PairCounts.Totals() incorrect: %v", totals)
+	}
+}
+
+func TestPairTokenizer_TokenizePairs(t *testing.T) {
+	p, err := NewPairTokenizer("", "word", time.Minute)
+	if err != nil {
+		t.Fatalf("NewPairTokenizer returned an error: %s", err)
+	}
+	buf := new(bytes.Buffer)
+
+	pc, _ := p.TokenizePairs(buf)
+	if len(pc.Counts) != 0 {
+		t.Error("TokenizePairs on empty reader didn't return empty PairCounts")
+	}
+
+	buf.WriteString("index 12:00:01\nindex 12:00:59\nlogin 12:01:30\n404 12:01:31\nnosecondary\n")
+	pc, _ = p.TokenizePairs(buf)
+	if len(pc.Counts) != 2 {
+		t.Errorf("TokenizePairs extracted wrong number of primary keys: %v", pc.Counts)
+	}
+	if pc.Counts["index"]["12:00:00"] != 2 || pc.Counts["login"]["12:01:00"] != 1 {
+		t.Errorf("TokenizePairs did not bucket secondary keys correctly: %v", pc.Counts)
+	}
+}
+
+func TestBucketTime(t *testing.T) {
+	testCases := []struct {
+		in       string
+		bucket   time.Duration
+		expected string
+	}{
+		{"2017-08-28T22:58:01Z", time.Minute, "2017-08-28T22:58:00Z"},
+		{"2017-08-28 22:58:01", time.Hour, "2017-08-28 22:00:00"},
+		{"28/Aug/2017:22:58:01 -0700", 5 * time.Minute, "28/Aug/2017:22:55:00 -0700"},
+		{"28/Aug/2017:22:58:01 -0700", 24 * time.Hour, "28/Aug/2017:00:00:00 -0700"},
+		{"2017-08-28T22:58:01+05:30", time.Hour, "2017-08-28T22:00:00+05:30"},
+		{"Aug 28 22:58:01", 10 * time.Second, "Aug 28 22:58:00"},
+		{"22:58:01", time.Minute, "22:58:00"},
+		{"1504000681", time.Minute, "1504000680"},
+		{"not a time", time.Minute, "not a time"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			actual := BucketTime(tc.in, tc.bucket)
+			if actual != tc.expected {
+				t.Errorf("BucketTime incorrect: expected %s; actual %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBucketRange(t *testing.T) {
+	testCases := []struct {
+		name     string
+		keys     []string
+		expected []string
+	}{
+		{"Gaps filled", []string{"12:20", "12:00", "12:05"}, []string{"12:00", "12:05", "12:10", "12:15", "12:20"}},
+		{"Time zone kept", []string{"2017-08-28T23:00:00+05:30", "2017-08-28T22:50:00+05:30"},
+			[]string{"2017-08-28T22:50:00+05:30", "2017-08-28T22:55:00+05:30", "2017-08-28T23:00:00+05:30"}},
+		{"Unix timestamps", []string{"1504000800", "1504000500"}, []string{"1504000500", "1504000800"}},
+		{"Not timestamps", []string{"b", "a"}, []string{"a", "b"}},
+		{"Mixed formats", []string{"12:05", "1504000500"}, []string{"12:05", "1504000500"}},
+		{"Too many buckets", []string{"2017-12-01", "2017-08-01"}, []string{"2017-08-01", "2017-12-01"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := BucketRange(tc.keys, 5*time.Minute)
+			if fmt.Sprint(actual) != fmt.Sprint(tc.expected) {
+				t.Errorf("BucketRange incorrect: expected %v; actual %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected time.Time
+		ok       bool
+	}{
+		{"2017-08-28T22:58:01Z", time.Date(2017, 8, 28, 22, 58, 1, 0, time.UTC), true},
+		{"1504000681", time.Unix(1504000681, 0), true},
+		{"1504000681.5", time.Unix(1504000681, 5e8), true},
+		{"not a time", time.Time{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			actual, ok := parseTimestamp(tc.in)
+			if ok != tc.ok || !actual.Equal(tc.expected) {
+				t.Errorf("parseTimestamp incorrect: expected %s; actual %s", tc.expected, actual)
+			}
+		})
+	}
+}
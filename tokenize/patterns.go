@@ -0,0 +1,123 @@
+package tokenize
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// patterns are the named regexps that can be given, after PatternSigil, in
+// place of a regexp to --match, --tokenize and the pipeline stages. Each
+// matches one token, and those that could otherwise match part of a longer run
+// of letters or digits are bounded by \b. A hex number needs its 0x, or every
+// word like "cafe", and every plain number, would be one.
+var patterns = map[string]string{
+	"word":          `[A-Z,a-z]+`,
+	"num":           `\d+`,
+	"number":        `-?\d+(?:\.\d+)?(?:[eE][-+]?\d+)?`,
+	"hex":           `\b0[xX][0-9A-Fa-f]+\b`,
+	"ipv4":          `\b(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(?:\.(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)){3}\b`,
+	"ipv6":          ipv6Pattern,
+	"mac":           `\b[0-9A-Fa-f]{2}(?::[0-9A-Fa-f]{2}){5}\b`,
+	"email":         `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	"url":           `[A-Za-z][A-Za-z0-9+.-]*://[^\s"'<>]+`,
+	"hostname":      `\b(?:[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?\.)+[A-Za-z](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?\b`,
+	"path":          `/[^\s"'?#]*`,
+	"uuid":          `\b[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}\b`,
+	"http-status":   `\b[1-5]\d\d\b`,
+	"iso-date":      `\b\d{4}-(?:0[1-9]|1[0-2])-(?:0[1-9]|[12]\d|3[01])\b`,
+	"iso-timestamp": `\b\d{4}-\d\d-\d\d[T ]\d\d:\d\d:\d\d(?:\.\d+)?(?:Z|[+-]\d\d:?\d\d)?`,
+}
+
+// ipv6Pattern matches the full and the compressed (::) forms of an IPv6
+// address
+const ipv6Pattern = `(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}` +
+	`|(?:[0-9A-Fa-f]{1,4}:){1,7}:` +
+	`|(?:[0-9A-Fa-f]{1,4}:){1,6}:[0-9A-Fa-f]{1,4}` +
+	`|(?:[0-9A-Fa-f]{1,4}:){1,5}(?::[0-9A-Fa-f]{1,4}){1,2}` +
+	`|(?:[0-9A-Fa-f]{1,4}:){1,4}(?::[0-9A-Fa-f]{1,4}){1,3}` +
+	`|(?:[0-9A-Fa-f]{1,4}:){1,3}(?::[0-9A-Fa-f]{1,4}){1,4}` +
+	`|(?:[0-9A-Fa-f]{1,4}:){1,2}(?::[0-9A-Fa-f]{1,4}){1,5}` +
+	`|[0-9A-Fa-f]{1,4}:(?::[0-9A-Fa-f]{1,4}){1,6}` +
+	`|::(?:[0-9A-Fa-f]{1,4}(?::[0-9A-Fa-f]{1,4}){0,6})?`
+
+// PatternSigil marks a named pattern, as in "@ipv4", so that no name can
+// change what a plain regexp such as "path" matches. The bare names "word"
+// and "num", which came before the registry, are still understood.
+const PatternSigil = "@"
+
+// namedPattern returns the regexp of the pattern s names, if s is PatternSigil
+// followed by the name of a pattern
+func namedPattern(s string) (string, bool) {
+	if !strings.HasPrefix(s, PatternSigil) {
+		return "", false
+	}
+	p, ok := patterns[strings.TrimPrefix(s, PatternSigil)]
+	return p, ok
+}
+
+// RegisterPattern names the regexp re so that it can be used like the
+// built-in patterns, replacing any pattern already of that name
+func RegisterPattern(name string, re string) error {
+	if _, err := regexp.Compile(re); err != nil {
+		return fmt.Errorf("pattern %s: %s", name, err)
+	}
+	patterns[name] = re
+	return nil
+}
+
+// PatternNames returns the name of every pattern, sorted
+func PatternNames() []string {
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compileMatcher compiles a --match regexp. A named pattern, or "word" or
+// "num", must match the whole of a token or line.
+func compileMatcher(matcher string) (*regexp.Regexp, error) {
+	switch matcher {
+	case "word", "num":
+		matcher = PatternSigil + matcher
+	}
+	if p, ok := namedPattern(matcher); ok {
+		matcher = `^(?:` + p + `)$`
+	}
+	re, err := regexp.Compile(matcher)
+	if err != nil {
+		return nil, fmt.Errorf("match regexp: %s", err)
+	}
+	return re, nil
+}
+
+// compileSplitter returns the Splitter for a --tokenize regexp. "white" and
+// "word" split at white space and non-word characters, and the tokens of a
+// named pattern are its matches. Any other regexp is split at.
+func compileSplitter(splitter string) (Splitter, error) {
+	switch splitter {
+	case "white":
+		return RegexpSplitter(regexp.MustCompile(WHITESPACE_REGEX)), nil
+	case "word":
+		return RegexpSplitter(regexp.MustCompile(WORD_SPLIT_REGEX)), nil
+	}
+
+	if p, ok := namedPattern(splitter); ok {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		// the longest match, so that "::1" is not cut short at "::"
+		re.Longest()
+		return RegexpFinder(re), nil
+	}
+
+	re, err := regexp.Compile(splitter)
+	if err != nil {
+		return nil, fmt.Errorf("split regexp: %s", err)
+	}
+	return RegexpSplitter(re), nil
+}
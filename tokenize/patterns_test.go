@@ -0,0 +1,93 @@
+package tokenize
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		input    string
+		expected []string
+	}{
+		{"ipv4", "from 10.0.0.1 to 192.168.1.254, not 1234.5.6.7 or 256.1.1.1", []string{"10.0.0.1", "192.168.1.254"}},
+		{"ipv6", "[2001:db8::1]:80 fe80::1ff:fe23:4567:890a ::1", []string{"2001:db8::1", "fe80::1ff:fe23:4567:890a", "::1"}},
+		{"email", "mail ann.lee+logs@example.co.uk now", []string{"ann.lee+logs@example.co.uk"}},
+		{"url", `href="https://example.com/a?b=1" ftp://x.org`, []string{"https://example.com/a?b=1", "ftp://x.org"}},
+		{"uuid", "id=123e4567-e89b-12d3-a456-426614174000;", []string{"123e4567-e89b-12d3-a456-426614174000"}},
+		{"hex", "0xdeadBEEF ff cafe 123 0x1f 0xzz", []string{"0xdeadBEEF", "0x1f"}},
+		{"hostname", "GET api.example.com and localhost", []string{"api.example.com"}},
+		{"http-status", `"GET / HTTP/1.1" 404 1234 200`, []string{"404", "200"}},
+		{"iso-date", "2017-06-01 and 2017-13-01", []string{"2017-06-01"}},
+		{"mac", "ether 00:1a:2B:3c:4d:5e up", []string{"00:1a:2B:3c:4d:5e"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			split, err := compileSplitter(PatternSigil + tc.pattern)
+			if err != nil {
+				t.Fatalf("compileSplitter returned an error: %s", err)
+			}
+			if actual := split(tc.input); fmt.Sprintf("%q", actual) != fmt.Sprintf("%q", tc.expected) {
+				t.Errorf("%s matches incorrect: expected %q; actual %q", tc.pattern, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCompileMatcher(t *testing.T) {
+	testCases := []struct {
+		matcher  string
+		input    string
+		expected bool
+	}{
+		{"word", "abc", true},
+		{"word", "abc1", false},
+		{"num", "42", true},
+		{"@word", "abc", true},
+		{"@ipv4", "10.0.0.1", true},
+		{"@hex", "0x1F", true},
+		{"@hex", "cafe", false},
+		{"@hex", "123", false},
+		{"@ipv4", "host 10.0.0.1", false},
+		{"ipv4", "10.0.0.1", false},
+		{"ip", "ship", true},
+		{"path", "footpath", true},
+		{"@nonesuch", "@nonesuch", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.matcher+" "+tc.input, func(t *testing.T) {
+			re, err := compileMatcher(tc.matcher)
+			if err != nil {
+				t.Fatalf("compileMatcher returned an error: %s", err)
+			}
+			if re.MatchString(tc.input) != tc.expected {
+				t.Errorf("%s matching %q incorrect: expected %t", tc.matcher, tc.input, tc.expected)
+			}
+		})
+	}
+}
+
+func TestRegisterPattern(t *testing.T) {
+	if err := RegisterPattern("ticket", `\bOPS-\d+\b`); err != nil {
+		t.Fatalf("RegisterPattern returned an error: %s", err)
+	}
+	defer delete(patterns, "ticket")
+
+	if err := RegisterPattern("broken", `(`); err == nil {
+		t.Error("RegisterPattern should reject a bad regexp")
+	}
+
+	r, err := NewRegexTokenizer("@ticket", ".")
+	if err != nil {
+		t.Fatalf("NewRegexTokenizer returned an error: %s", err)
+	}
+	actual, _ := r.Tokenize(bytes.NewBufferString("fixed OPS-12, see OPS-12 and OPS-7\n"))
+	expected := map[string]float64{"OPS-12": 2, "OPS-7": 1}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Tokenize with a registered pattern incorrect: expected %v; actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,375 @@
+package tokenize
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Token is a key to count and the amount to count it by
+type Token struct {
+	Key   string
+	Value float64
+	// Secondary is a second key, such as a user for a key that is an
+	// endpoint, for Counters that count distinct secondary keys
+	Secondary string
+}
+
+// Splitter breaks a record into the strings to count
+type Splitter func(record string) []string
+
+// Extractor makes a Token of a string from the Splitter, or reports false if
+// there is nothing in it to count
+type Extractor func(s string) (Token, bool)
+
+// Parser makes the Tokens of a record directly, in place of a Splitter and
+// Extractor
+type Parser func(record string) []Token
+
+// Counter tallies Tokens
+type Counter interface {
+	// Add counts t
+	Add(t Token)
+	// Counts returns the tally for each key
+	Counts() map[string]float64
+	// Len returns the number of distinct keys counted
+	Len() int
+}
+
+// Pipeline is a Tokenizer put together from stages. Each record read, less any
+// trailing newline, is broken into strings by its Splitter and each string is
+// made a Token by its Extractor. The Token then goes through the filters and
+// transforms in the order they were added, and those that are left are
+// counted by a Counter. A stage that is not set passes its input on as it is:
+// by default the whole record is the key, and it counts once.
+type Pipeline struct {
+	options
+	parser     Parser
+	splitter   Splitter
+	extractor  Extractor
+	stages     []func(Token) (Token, bool)
+	newCounter func() Counter
+}
+
+// NewPipeline returns a Pipeline that counts each distinct record, to which
+// stages can be added with its other methods, eg
+//
+//	NewPipeline().Split(RegexpSplitter(re)).Filter(MatchKey(word)).Transform(lower)
+//
+// It sums the values for each key unless given WithCounter.
+func NewPipeline(opts ...Option) *Pipeline {
+	p := &Pipeline{options: newOptions(opts), newCounter: NewSumCounter}
+	if p.options.newCounter != nil {
+		p.newCounter = p.options.newCounter
+	}
+	return p
+}
+
+// Split sets the Splitter that breaks each record into strings
+func (p *Pipeline) Split(splitter Splitter) *Pipeline {
+	p.splitter = splitter
+	return p
+}
+
+// Parse sets the Parser that makes the Tokens of each record, in place of any
+// Splitter and Extractor
+func (p *Pipeline) Parse(parser Parser) *Pipeline {
+	p.parser = parser
+	return p
+}
+
+// Extract sets the Extractor that makes a Token of each string
+func (p *Pipeline) Extract(extractor Extractor) *Pipeline {
+	p.extractor = extractor
+	return p
+}
+
+// Filter adds a stage that drops the Tokens keep returns false for
+func (p *Pipeline) Filter(keep func(Token) bool) *Pipeline {
+	p.stages = append(p.stages, func(t Token) (Token, bool) {
+		return t, keep(t)
+	})
+	return p
+}
+
+// Transform adds a stage that replaces each Token with what transform returns
+func (p *Pipeline) Transform(transform func(Token) Token) *Pipeline {
+	p.stages = append(p.stages, func(t Token) (Token, bool) {
+		return transform(t), true
+	})
+	return p
+}
+
+// Count sets how Tokens are tallied. newCounter is called for a fresh Counter
+// each time the Pipeline tokenizes its input.
+func (p *Pipeline) Count(newCounter func() Counter) *Pipeline {
+	p.newCounter = newCounter
+	return p
+}
+
+func (p *Pipeline) Tokenize(reader io.Reader) (map[string]float64, error) {
+	counter := p.newCounter()
+
+	err := p.scan(reader, counter.Len, func(record string) (int, int, error) {
+		record = strings.TrimRight(record, "\n")
+		if p.parser != nil {
+			return p.countParsed(counter, record)
+		}
+
+		strs := []string{record}
+		if p.splitter != nil {
+			strs = p.splitter(record)
+		}
+
+		matched := 0
+		for _, s := range strs {
+			if t, ok := p.token(s); ok {
+				counter.Add(t)
+				matched++
+			}
+		}
+		return len(strs), matched, nil
+	})
+	if err != nil && err != ErrInterrupted {
+		return nil, err
+	}
+
+	return counter.Counts(), err
+}
+
+// countParsed counts the Tokens the Parser makes of record that get through
+// the filters and transforms
+func (p *Pipeline) countParsed(counter Counter, record string) (int, int, error) {
+	tokens := p.parser(record)
+	matched := 0
+	for _, t := range tokens {
+		if t, ok := p.stage(t); ok {
+			counter.Add(t)
+			matched++
+		}
+	}
+	return len(tokens), matched, nil
+}
+
+// token takes s through the Extractor, filters and transforms
+func (p *Pipeline) token(s string) (Token, bool) {
+	t := Token{Key: s, Value: 1}
+	if p.extractor != nil {
+		var ok bool
+		if t, ok = p.extractor(s); !ok {
+			return t, false
+		}
+	}
+	return p.stage(t)
+}
+
+// stage takes t through the filters and transforms
+func (p *Pipeline) stage(t Token) (Token, bool) {
+	for _, stage := range p.stages {
+		var ok bool
+		if t, ok = stage(t); !ok {
+			return t, false
+		}
+	}
+	return t, true
+}
+
+// RegexpSplitter returns a Splitter that splits records at each match of re,
+// as --tokenize does
+func RegexpSplitter(re *regexp.Regexp) Splitter {
+	return func(record string) []string {
+		return re.Split(record, -1)
+	}
+}
+
+// RegexpExtractor returns an Extractor that takes the key from the group of re
+// named "key", and the value, which must be a number, from the group named
+// "value". If re names none of its groups, the key is its first group and the
+// value its second. Without a key group the key is the whole match, and
+// without a value group every key counts once. The secondary key is taken from
+// any group named "secondary".
+func RegexpExtractor(re *regexp.Regexp) Extractor {
+	keyIdx, valueIdx, secondaryIdx := 0, -1, -1
+	named := false
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			named = true
+		}
+		if name == "key" {
+			keyIdx = i
+		} else if name == "value" {
+			valueIdx = i
+		} else if name == "secondary" {
+			secondaryIdx = i
+		}
+	}
+	if !named && re.NumSubexp() >= 1 {
+		keyIdx = 1
+	}
+	if !named && re.NumSubexp() >= 2 {
+		valueIdx = 2
+	}
+
+	return func(s string) (Token, bool) {
+		res := re.FindStringSubmatch(s)
+		if res == nil {
+			return Token{}, false
+		}
+		t := Token{Key: res[keyIdx], Value: 1}
+		if secondaryIdx >= 0 {
+			t.Secondary = res[secondaryIdx]
+		}
+		if valueIdx >= 0 {
+			value, err := strconv.ParseFloat(res[valueIdx], 64)
+			if err != nil {
+				return Token{}, false
+			}
+			t.Value = value
+		}
+		return t, true
+	}
+}
+
+// RegexpFinder returns a Splitter that breaks records into the matches of re
+func RegexpFinder(re *regexp.Regexp) Splitter {
+	return func(record string) []string {
+		return re.FindAllString(record, -1)
+	}
+}
+
+// MatchKey returns a filter that keeps the Tokens whose keys match re, as
+// --match does
+func MatchKey(re *regexp.Regexp) func(Token) bool {
+	return func(t Token) bool {
+		return re.MatchString(t.Key)
+	}
+}
+
+type sumCounter map[string]float64
+
+// NewSumCounter returns a Counter that adds up the values for each key
+func NewSumCounter() Counter {
+	return sumCounter{}
+}
+
+func (c sumCounter) Add(t Token) {
+	c[t.Key] += t.Value
+}
+
+func (c sumCounter) Counts() map[string]float64 {
+	return c
+}
+
+func (c sumCounter) Len() int {
+	return len(c)
+}
+
+type lastCounter struct {
+	sumCounter
+}
+
+// NewLastCounter returns a Counter that keeps the last value for each key, as
+// for input that is already tallied
+func NewLastCounter() Counter {
+	return lastCounter{sumCounter{}}
+}
+
+func (c lastCounter) Add(t Token) {
+	c.sumCounter[t.Key] = t.Value
+}
+
+// extractShortcuts are the regexps for the extract stage's shortcuts, which
+// read input that is already tallied as --graph does
+var extractShortcuts = map[string]string{
+	"kv": `^\s*(?P<key>.+)\s+(?P<value>-?\d+(?:\.\d+)?)$`,
+	"vk": `^\s*(?P<value>-?\d+(?:\.\d+)?)\s+(?P<key>.+)$`,
+}
+
+// ParsePipeline returns a Pipeline built from stages, as given by --stage
+// flags. Each is a name, or a name and an argument after a colon:
+//
+//	split:RE    split records at matches of RE, or at "white" space or "word"
+//	            boundaries, or into the matches of a named pattern such as
+//	            "@ipv4", as for --tokenize
+//	extract:RE  key (and value and secondary key) as RegexpExtractor takes
+//	            them, or "kv" or "vk"
+//	match:RE    count only keys matching RE, or all of a named pattern as for
+//	            --match
+//	exclude:RE  drop keys matching RE or all of a named pattern
+//	lower       lower-case keys
+//	upper       upper-case keys
+//	trim        trim space from around keys
+//	bucket:D    round keys that are timestamps down to a multiple of duration D
+//	count:A     how to tally values, by an aggregate of NewAggregateCounter
+//	            (default sum)
+//
+// Filters and transforms apply in the order given.
+func ParsePipeline(stages []string, opts ...Option) (*Pipeline, error) {
+	p := NewPipeline(opts...)
+
+	for _, stage := range stages {
+		parts := strings.SplitN(stage, ":", 2)
+		name, arg := parts[0], ""
+		if len(parts) > 1 {
+			arg = parts[1]
+		}
+
+		switch name {
+		case "split":
+			split, err := compileSplitter(arg)
+			if err != nil {
+				return nil, err
+			}
+			p.Split(split)
+		case "extract":
+			re, err := compileShortcut(arg, extractShortcuts)
+			if err != nil {
+				return nil, err
+			}
+			p.Extract(RegexpExtractor(re))
+		case "match", "exclude":
+			re, err := compileMatcher(arg)
+			if err != nil {
+				return nil, err
+			}
+			if name == "match" {
+				p.Filter(MatchKey(re))
+			} else {
+				p.Filter(func(t Token) bool { return !re.MatchString(t.Key) })
+			}
+		case "lower":
+			p.Transform(func(t Token) Token { t.Key = strings.ToLower(t.Key); return t })
+		case "upper":
+			p.Transform(func(t Token) Token { t.Key = strings.ToUpper(t.Key); return t })
+		case "trim":
+			p.Transform(func(t Token) Token { t.Key = strings.TrimSpace(t.Key); return t })
+		case "bucket":
+			d, err := time.ParseDuration(arg)
+			if err != nil {
+				return nil, err
+			}
+			p.Transform(func(t Token) Token { t.Key = BucketTime(t.Key, d); return t })
+		case "count":
+			newCounter, err := NewAggregateCounter(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%s in stage %q", err, stage)
+			}
+			p.Count(newCounter)
+		default:
+			return nil, fmt.Errorf("unknown stage %q", stage)
+		}
+	}
+
+	return p, nil
+}
+
+// compileShortcut compiles re, or the regexp it is a shortcut for
+func compileShortcut(re string, shortcuts map[string]string) (*regexp.Regexp, error) {
+	if expanded, ok := shortcuts[re]; ok {
+		re = expanded
+	}
+	return regexp.Compile(re)
+}
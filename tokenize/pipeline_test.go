@@ -0,0 +1,78 @@
+package tokenize
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestPipeline_Tokenize(t *testing.T) {
+	p := NewPipeline().
+		Split(RegexpSplitter(regexp.MustCompile(`\s+`))).
+		Transform(func(t Token) Token { t.Key = strings.ToLower(t.Key); return t }).
+		Filter(MatchKey(regexp.MustCompile(`^[a-z]+$`)))
+
+	actual, err := p.Tokenize(bytes.NewBufferString("The cat\nthe 2 cats\n"))
+	if err != nil {
+		t.Fatalf("Tokenize returned an error: %s", err)
+	}
+	expected := map[string]float64{"the": 2, "cat": 1, "cats": 1}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Tokenize incorrect: expected %v; actual %v", expected, actual)
+	}
+
+	// each run starts a fresh count
+	actual, _ = p.Tokenize(bytes.NewBufferString("cat\n"))
+	if len(actual) != 1 || actual["cat"] != 1 {
+		t.Errorf("Tokenize should not carry counts between runs: %v", actual)
+	}
+}
+
+func TestParsePipeline(t *testing.T) {
+	testCases := []struct {
+		name     string
+		stages   []string
+		input    string
+		expected map[string]float64
+	}{
+		{"No stages", nil, "a\nb\na\n", map[string]float64{"a": 2, "b": 1}},
+		{"Split and match", []string{"split:white", "match:word"}, "a 1 b\nb\n", map[string]float64{"a": 1, "b": 2}},
+		{"Exclude", []string{"split:,", "exclude:^b"}, "a,b,bc,c\n", map[string]float64{"a": 1, "c": 1}},
+		{"Transforms in order", []string{"trim", "upper", "match:^A"}, " a \nb\n", map[string]float64{"A": 1}},
+		{"Named pattern", []string{"split:@ipv4", "match:@ipv4"}, "from 10.0.0.1 to 10.0.0.1:80\n", map[string]float64{"10.0.0.1": 2}},
+		{"Extract sums values", []string{"extract:kv"}, "a 2\nb 1\na 3\n", map[string]float64{"a": 5, "b": 1}},
+		{"Extract last value", []string{"extract:vk", "count:last"}, "2 a\n1 b\n3 a\n", map[string]float64{"a": 3, "b": 1}},
+		{"Extract named groups", []string{`extract:(?P<value>\d+)ms (?P<key>\S+)`}, "5ms /a\n7ms /a\nslow\n", map[string]float64{"/a": 12}},
+		{"Extract named key second", []string{`extract:(?P<v>\d+) (?P<key>\w+)`}, "5 a\n7 a\n2 b\n", map[string]float64{"a": 2, "b": 1}},
+		{"Extract key only", []string{`extract:user=(\w+)`, "lower"}, "user=Ann\nuser=ann x\n", map[string]float64{"ann": 2}},
+		{"Bucket", []string{"bucket:1m"}, "12:01:05\n12:01:55\n12:02:00\n", map[string]float64{"12:01:00": 2, "12:02:00": 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ParsePipeline(tc.stages)
+			if err != nil {
+				t.Fatalf("ParsePipeline returned an error: %s", err)
+			}
+			actual, err := p.Tokenize(bytes.NewBufferString(tc.input))
+			if err != nil {
+				t.Fatalf("Tokenize returned an error: %s", err)
+			}
+			if fmt.Sprint(actual) != fmt.Sprint(tc.expected) {
+				t.Errorf("Tokenize incorrect: expected %v; actual %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParsePipeline_Errors(t *testing.T) {
+	for _, stages := range [][]string{{"reverse"}, {"split:("}, {"count:p101"}, {"bucket:soon"}} {
+		t.Run(stages[0], func(t *testing.T) {
+			if _, err := ParsePipeline(stages); err == nil {
+				t.Errorf("ParsePipeline should reject %v", stages)
+			}
+		})
+	}
+}
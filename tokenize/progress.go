@@ -0,0 +1,76 @@
+package tokenize
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Progress is how far a Tokenizer has got through its input
+type Progress struct {
+	// Lines is the number of records read
+	Lines uint64
+	// Bytes is the number of bytes read
+	Bytes uint64
+	// Examined is the number of tokens (or lines) looked at
+	Examined uint64
+	// Matched is the number of tokens (or lines) counted
+	Matched uint64
+	// Keys is the number of distinct keys counted
+	Keys int
+	// Done is set once the input is finished
+	Done bool
+}
+
+// WithProgress has a Tokenizer call report with its progress every interval
+// while it reads, however few records arrive in that time, and once more when
+// the input is finished. An interval of zero reports after every record.
+func WithProgress(interval time.Duration, report func(Progress)) Option {
+	return func(o *options) {
+		o.progress = report
+		o.interval = interval
+	}
+}
+
+// NewProgressWriter returns a report function for WithProgress that keeps a
+// single status line up to date on writer, which should be a terminal, by
+// rewriting it after a carriage return. size is the length of the input in
+// bytes, or 0 if that is not known. When the input is finished the line is
+// blanked so that whatever is written next can take its place.
+func NewProgressWriter(writer io.Writer, size int64) func(Progress) {
+	start := time.Now()
+	width := 0
+
+	return func(p Progress) {
+		if p.Done {
+			if width > 0 {
+				io.WriteString(writer, "\r"+strings.Repeat(" ", width)+"\r")
+			}
+			return
+		}
+
+		line := fmt.Sprintf("lines read: %s (%s", humanize.Comma(int64(p.Lines)), humanize.Bytes(p.Bytes))
+		if size > 0 {
+			line += fmt.Sprintf(", %.0f%%", float64(p.Bytes)/float64(size)*100)
+		}
+		line += fmt.Sprintf(") matched: %s keys: %s", humanize.Comma(int64(p.Matched)), humanize.Comma(int64(p.Keys)))
+		if secs := time.Since(start).Seconds(); secs > 0 {
+			line += fmt.Sprintf(" at %s lines/s (%s/s)",
+				humanize.Comma(int64(float64(p.Lines)/secs)),
+				humanize.Bytes(uint64(float64(p.Bytes)/secs)))
+		}
+
+		// pad over anything left from a longer line before
+		n := utf8.RuneCountInString(line)
+		if n < width {
+			line += strings.Repeat(" ", width-n)
+		} else {
+			width = n
+		}
+		io.WriteString(writer, "\r"+line)
+	}
+}
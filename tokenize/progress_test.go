@@ -0,0 +1,36 @@
+package tokenize
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewProgressWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	write := NewProgressWriter(buf, 2000)
+
+	write(Progress{Lines: 1234, Bytes: 1000, Matched: 1000, Keys: 12})
+	line := buf.String()
+	expected := "\rlines read: 1,234 (1.0 kB, 50%) matched: 1,000 keys: 12"
+	if !strings.HasPrefix(line, expected) {
+		t.Errorf("progress line incorrect: expected %q; actual %q", expected, line)
+	}
+
+	buf.Reset()
+	write(Progress{Done: true})
+	blank := "\r" + strings.Repeat(" ", len(line)-1) + "\r"
+	if buf.String() != blank {
+		t.Errorf("progress line not blanked: expected %q; actual %q", blank, buf.String())
+	}
+}
+
+func TestNewProgressWriter_Silent(t *testing.T) {
+	buf := new(bytes.Buffer)
+	write := NewProgressWriter(buf, 0)
+
+	write(Progress{Done: true})
+	if buf.Len() != 0 {
+		t.Errorf("nothing should be written for a run too short to report on: %q", buf.String())
+	}
+}
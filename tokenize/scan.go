@@ -0,0 +1,185 @@
+package tokenize
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Option configures how a Tokenizer reads its input
+type Option func(*options)
+
+type options struct {
+	progress      func(Progress)
+	interval      time.Duration
+	ctx           context.Context
+	maxRecordSize int
+	split         bufio.SplitFunc
+	newCounter    func() Counter
+}
+
+// DefaultMaxRecordSize is the longest record a Tokenizer reads unless
+// WithMaxRecordSize says otherwise
+const DefaultMaxRecordSize = 1024 * 1024
+
+// WithMaxRecordSize sets the longest record, in bytes, a Tokenizer reads. A
+// longer record is an error rather than the end of the input.
+func WithMaxRecordSize(n int) Option {
+	return func(o *options) {
+		o.maxRecordSize = n
+	}
+}
+
+// WithSeparator has a Tokenizer split its input into records with split
+// instead of at line ends. See SplitString and SplitRegexp.
+func WithSeparator(split bufio.SplitFunc) Option {
+	return func(o *options) {
+		o.split = split
+	}
+}
+
+// ErrInterrupted is returned, along with everything counted so far, by a
+// Tokenizer whose context was cancelled before the end of its input
+var ErrInterrupted = errors.New("input truncated by interrupt")
+
+// WithContext has a Tokenizer stop reading once ctx is cancelled, even if it
+// is waiting for more input, as from `tail -f`
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// interrupted reports whether the context has been cancelled
+func (o options) interrupted() bool {
+	return o.ctx != nil && o.ctx.Err() != nil
+}
+
+func newOptions(opts []Option) options {
+	o := options{maxRecordSize: DefaultMaxRecordSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// scan reads reader a record at a time, passing each to record, which returns
+// how many tokens it examined and how many of those it counted. keys returns
+// how many distinct keys have been counted so far. Reading stops at the first
+// error record returns or reading returns, or with ErrInterrupted if the
+// context is cancelled.
+func (o options) scan(reader io.Reader, keys func() int, record func(string) (int, int, error)) error {
+	if o.ctx != nil {
+		reader = &cancellableReader{ctx: o.ctx, reader: reader}
+	}
+	counter := &countingReader{reader: reader}
+	scanner := bufio.NewScanner(counter)
+	initial := bufio.MaxScanTokenSize
+	if o.maxRecordSize < initial {
+		initial = o.maxRecordSize
+	}
+	scanner.Buffer(make([]byte, initial), o.maxRecordSize)
+	if o.split != nil {
+		scanner.Split(o.split)
+	}
+
+	p := Progress{}
+	report := func(done bool) {
+		if o.progress != nil {
+			p.Bytes, p.Keys, p.Done = counter.n, keys(), done
+			o.progress(p)
+		}
+	}
+
+	// the ticker only marks a report as due, which is made between records so
+	// that the counts are not read while they are being changed
+	var tick <-chan time.Time
+	if o.progress != nil && o.interval > 0 {
+		ticker := time.NewTicker(o.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for scanner.Scan() {
+		if o.interrupted() {
+			break
+		}
+		examined, matched, err := record(scanner.Text())
+		if err != nil {
+			return err
+		}
+		p.Lines++
+		p.Examined += uint64(examined)
+		p.Matched += uint64(matched)
+
+		if o.progress != nil && o.interval <= 0 {
+			report(false)
+			continue
+		}
+		select {
+		case <-tick:
+			report(false)
+		default:
+		}
+	}
+	report(true)
+
+	if o.interrupted() {
+		return ErrInterrupted
+	}
+	if err := scanner.Err(); err == bufio.ErrTooLong {
+		return fmt.Errorf("record %d is longer than %d bytes", p.Lines+1, o.maxRecordSize)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	n      uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
+
+// cancellableReader abandons a read that is waiting for input when its
+// context is cancelled
+type cancellableReader struct {
+	ctx    context.Context
+	reader io.Reader
+	// buf is read into in place of the caller's buffer, which must not be
+	// written to once Read has returned
+	buf []byte
+}
+
+func (c *cancellableReader) Read(p []byte) (int, error) {
+	if c.ctx.Err() != nil {
+		return 0, ErrInterrupted
+	}
+	if len(c.buf) < len(p) {
+		c.buf = make([]byte, len(p))
+	}
+	buf := c.buf[:len(p)]
+
+	var n int
+	var err error
+	done := make(chan struct{})
+	go func() {
+		n, err = c.reader.Read(buf)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return copy(p, buf[:n]), err
+	case <-c.ctx.Done():
+		return 0, ErrInterrupted
+	}
+}
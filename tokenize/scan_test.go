@@ -0,0 +1,143 @@
+package tokenize
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestOptions_Scan(t *testing.T) {
+	var reports []Progress
+	o := newOptions([]Option{WithProgress(time.Hour, func(p Progress) { reports = append(reports, p) })})
+
+	lines := bytes.Repeat([]byte("a b\n"), 5)
+	err := o.scan(bytes.NewBuffer(lines), func() int { return 7 }, func(line string) (int, int, error) {
+		return 2, 1, nil
+	})
+	if err != nil {
+		t.Fatalf("scan returned an error: %s", err)
+	}
+
+	if len(reports) != 1 {
+		t.Fatalf("scan should report only at the end before the interval is up: %+v", reports)
+	}
+	expected := Progress{Lines: 5, Bytes: uint64(len(lines)), Examined: 10, Matched: 5, Keys: 7, Done: true}
+	if reports[0] != expected {
+		t.Errorf("scan progress incorrect: expected %+v; actual %+v", expected, reports[0])
+	}
+}
+
+// slowReader delivers one line per read, after a delay
+type slowReader struct {
+	lines []string
+	delay time.Duration
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if len(r.lines) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(r.delay)
+	n := copy(p, r.lines[0])
+	r.lines = r.lines[1:]
+	return n, nil
+}
+
+func TestOptions_ScanProgressSlowInput(t *testing.T) {
+	var reports []Progress
+	o := newOptions([]Option{WithProgress(10*time.Millisecond, func(p Progress) { reports = append(reports, p) })})
+
+	reader := &slowReader{lines: []string{"a\n", "b\n", "c\n"}, delay: 30 * time.Millisecond}
+	err := o.scan(reader, func() int { return 0 }, func(line string) (int, int, error) {
+		return 1, 1, nil
+	})
+	if err != nil {
+		t.Fatalf("scan returned an error: %s", err)
+	}
+
+	if len(reports) < 2 || reports[0].Done || !reports[len(reports)-1].Done {
+		t.Fatalf("scan should report along the way however few records there are: %+v", reports)
+	}
+	if reports[0].Lines == 0 || reports[0].Lines > 3 {
+		t.Errorf("scan progress incorrect: %+v", reports[0])
+	}
+}
+
+func TestOptions_ScanError(t *testing.T) {
+	o := newOptions(nil)
+	failure := errors.New("bad record")
+
+	read := 0
+	err := o.scan(bytes.NewBufferString("a\nb\nc\n"), func() int { return 0 }, func(line string) (int, int, error) {
+		read++
+		if line == "b" {
+			return 1, 0, failure
+		}
+		return 1, 1, nil
+	})
+	if err != failure || read != 2 {
+		t.Errorf("scan should stop at the first error: returned %v after %d records", err, read)
+	}
+}
+
+func TestOptions_ScanInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	o := newOptions([]Option{WithContext(ctx)})
+
+	// the writer is never closed, so reading blocks after the first two lines
+	// until the context is cancelled
+	reader, writer := io.Pipe()
+	go writer.Write([]byte("a\nb\n"))
+
+	var read []string
+	err := o.scan(reader, func() int { return len(read) }, func(line string) (int, int, error) {
+		read = append(read, line)
+		if len(read) == 2 {
+			cancel()
+		}
+		return 1, 1, nil
+	})
+	if err != ErrInterrupted || len(read) != 2 {
+		t.Errorf("scan should stop with ErrInterrupted once cancelled: returned %v after %v", err, read)
+	}
+}
+
+func TestLineTokenizer_TokenizeInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l, _ := NewLineTokenizer(".", WithContext(ctx))
+
+	tc, err := l.Tokenize(bytes.NewBufferString("a\n"))
+	if err != ErrInterrupted || tc == nil {
+		t.Errorf("Tokenize should return what was counted along with ErrInterrupted: returned %v, %v", tc, err)
+	}
+}
+
+func TestOptions_ScanLongRecord(t *testing.T) {
+	o := newOptions([]Option{WithMaxRecordSize(8)})
+
+	read := 0
+	err := o.scan(bytes.NewBufferString("short\nmuch too long\nshort\n"), func() int { return 0 }, func(line string) (int, int, error) {
+		read++
+		return 1, 1, nil
+	})
+	if err == nil || err.Error() != "record 2 is longer than 8 bytes" || read != 1 {
+		t.Errorf("scan should stop with an error at a long record: returned %v after %d records", err, read)
+	}
+}
+
+func TestOptions_ScanSeparator(t *testing.T) {
+	o := newOptions([]Option{WithSeparator(SplitString("\x00"))})
+
+	var read []string
+	err := o.scan(bytes.NewBufferString("a b\nc\x00d\x00"), func() int { return 0 }, func(line string) (int, int, error) {
+		read = append(read, line)
+		return 1, 1, nil
+	})
+	if err != nil || len(read) != 2 || read[0] != "a b\nc" || read[1] != "d" {
+		t.Errorf("scan should read records between separators: returned %v after %q", err, read)
+	}
+}
@@ -0,0 +1,47 @@
+package tokenize
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+)
+
+// SplitString returns a split function for WithSeparator that ends each record
+// at sep, such as "\x00" for the output of `find -print0`
+func SplitString(sep string) bufio.SplitFunc {
+	separator := []byte(sep)
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, separator); i >= 0 {
+			return i + len(separator), data[:i], nil
+		}
+		return splitRemainder(data, atEOF)
+	}
+}
+
+// SplitRegexp returns a split function for WithSeparator that ends each record
+// at a match of re. Matches of the empty string are ignored.
+func SplitRegexp(re *regexp.Regexp) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		for _, loc := range re.FindAllIndex(data, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			// a match that reaches the end of what has been read so far might
+			// go on further, as `\n+` would
+			if loc[1] == len(data) && !atEOF {
+				break
+			}
+			return loc[1], data[:loc[0]], nil
+		}
+		return splitRemainder(data, atEOF)
+	}
+}
+
+// splitRemainder asks for more data, or at the end of the input makes what is
+// left the last record
+func splitRemainder(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,44 @@
+package tokenize
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	testCases := []struct {
+		name     string
+		split    bufio.SplitFunc
+		input    string
+		expected []string
+	}{
+		{"NUL", SplitString("\x00"), "a b\x00c\x00", []string{"a b", "c"}},
+		{"String, no trailing separator", SplitString("--"), "a--b-c", []string{"a", "b-c"}},
+		{"Empty records", SplitString(","), "a,,b", []string{"a", "", "b"}},
+		{"Regexp", SplitRegexp(regexp.MustCompile(`\n\s*\n`)), "a\nb\n\n c\n  \nd", []string{"a\nb", " c", "d"}},
+		{"Regexp, empty matches ignored", SplitRegexp(regexp.MustCompile(`;*`)), "a;;b", []string{"a", "b"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// a tiny buffer makes the separators straddle reads
+			scanner := bufio.NewScanner(bytes.NewBufferString(tc.input))
+			scanner.Buffer(make([]byte, 2), 64)
+			scanner.Split(tc.split)
+
+			var actual []string
+			for scanner.Scan() {
+				actual = append(actual, scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("Scan returned an error: %s", err)
+			}
+			if fmt.Sprintf("%q", actual) != fmt.Sprintf("%q", tc.expected) {
+				t.Errorf("split incorrect: expected %q; actual %q", tc.expected, actual)
+			}
+		})
+	}
+}
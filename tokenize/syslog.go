@@ -0,0 +1,225 @@
+package tokenize
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// syslogMessage is a syslog line split into its parts. Parts the line does
+// not have are empty, and pri is -1.
+type syslogMessage struct {
+	pri            int
+	timestamp      string
+	host           string
+	appName        string
+	pid            string
+	msgid          string
+	structuredData string
+	message        string
+}
+
+var (
+	// rfc5424Regex matches <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID
+	// MSGID STRUCTURED-DATA [MSG]
+	rfc5424Regex = regexp.MustCompile(`^<(\d{1,3})>\d{1,2} (\S+) (\S+) (\S+) (\S+) (\S+) (-|(?:\[(?:[^\]\\]|\\.)*\])+)(?: (.*))?$`)
+	// rfc3164Regex matches [<PRI>]TIMESTAMP [HOSTNAME] TAG[PID]: MSG, as
+	// written to /var/log/syslog. The timestamp may also be RFC 3339, or a
+	// date and time, and the hostname, which cannot end in a colon as a tag
+	// does, may be missing.
+	rfc3164Regex = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d|\d{4}-\d\d-\d\d(?:T|\s+)\d\d:\d\d:\d\d\S*)\s+(?:(\S*[^\s:])\s+)?([^:\[\s]+)(?:\[(\d+)\])?: ?(.*)$`)
+
+	sdElementRegex = regexp.MustCompile(`\[([^\s\]]+)((?:\s+[^=\s\]]+="(?:[^"\\]|\\.)*")*)\s*\]`)
+	sdParamRegex   = regexp.MustCompile(`([^=\s\]]+)="((?:[^"\\]|\\.)*)"`)
+	sdUnescaper    = strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\]`, `]`)
+)
+
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var syslogSeverities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// parseSyslog parses an RFC 5424 or RFC 3164 syslog line
+func parseSyslog(line string) (syslogMessage, bool) {
+	if res := rfc5424Regex.FindStringSubmatch(line); res != nil {
+		m := syslogMessage{
+			pri:            parsePri(res[1]),
+			timestamp:      res[2],
+			host:           res[3],
+			appName:        res[4],
+			pid:            res[5],
+			msgid:          res[6],
+			structuredData: res[7],
+			message:        res[8],
+		}
+		// RFC 5424 writes a part that is missing as "-"
+		for _, part := range []*string{&m.timestamp, &m.host, &m.appName, &m.pid, &m.msgid, &m.structuredData} {
+			if *part == "-" {
+				*part = ""
+			}
+		}
+		return m, true
+	}
+
+	if res := rfc3164Regex.FindStringSubmatch(line); res != nil {
+		return syslogMessage{
+			pri:       parsePri(res[1]),
+			timestamp: res[2],
+			host:      res[3],
+			appName:   res[4],
+			pid:       res[5],
+			message:   res[6],
+		}, true
+	}
+
+	return syslogMessage{}, false
+}
+
+// parsePri parses the PRI of a syslog line, or returns -1 if there is none
+func parsePri(s string) int {
+	pri, err := strconv.Atoi(s)
+	if err != nil || pri/8 >= len(syslogFacilities) {
+		return -1
+	}
+	return pri
+}
+
+// NewSyslogTokenizer returns a Tokenizer for RFC 3164 or RFC 5424 syslog
+// lines that counts the values of a field that match matcher. The fields are
+// timestamp, host, app-name (the default Key), pid, msgid, facility, severity
+// and message. "sd" is the SD-IDs of the structured data elements, and
+// "sd:NAME" or "sd:ID:NAME" the values of the parameter NAME in any element,
+// or only in those with that SD-ID.
+func NewSyslogTokenizer(fields LogFields, matcher string, opts ...Option) (Tokenizer, error) {
+	if fields.Key == "" {
+		fields.Key = "app-name"
+	}
+	keys, err := syslogField(fields.Key)
+	if err != nil {
+		return nil, err
+	}
+	keyMatcher, err := compileMatcher(matcher)
+	if err != nil {
+		return nil, err
+	}
+	var values, distincts func(syslogMessage) []string
+	if fields.Value != "" {
+		if values, err = syslogField(fields.Value); err != nil {
+			return nil, err
+		}
+	}
+	if fields.Distinct != "" {
+		if distincts, err = syslogField(fields.Distinct); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewPipeline(fields.options(opts)...).Parse(func(line string) []Token {
+		m, ok := parseSyslog(line)
+		if !ok {
+			return nil
+		}
+
+		value := 1.0
+		if values != nil {
+			v := values(m)
+			if len(v) == 0 {
+				return nil
+			}
+			parsed, err := strconv.ParseFloat(v[0], 64)
+			if err != nil {
+				return nil
+			}
+			value = parsed
+		}
+
+		var tokens []Token
+		for _, key := range keys(m) {
+			if distincts == nil {
+				tokens = append(tokens, Token{Key: key, Value: value})
+				continue
+			}
+			// a key for each distinct value, where the field has several
+			for _, secondary := range distincts(m) {
+				tokens = append(tokens, Token{Key: key, Value: value, Secondary: secondary})
+			}
+		}
+		return tokens
+	}).Filter(MatchKey(keyMatcher)), nil
+}
+
+// syslogField returns a function that gets the values of field from a message
+func syslogField(field string) (func(syslogMessage) []string, error) {
+	part := func(get func(syslogMessage) string) func(syslogMessage) []string {
+		return func(m syslogMessage) []string {
+			if s := get(m); s != "" {
+				return []string{s}
+			}
+			return nil
+		}
+	}
+
+	switch field {
+	case "timestamp":
+		return part(func(m syslogMessage) string { return m.timestamp }), nil
+	case "host":
+		return part(func(m syslogMessage) string { return m.host }), nil
+	case "app-name":
+		return part(func(m syslogMessage) string { return m.appName }), nil
+	case "pid":
+		return part(func(m syslogMessage) string { return m.pid }), nil
+	case "msgid":
+		return part(func(m syslogMessage) string { return m.msgid }), nil
+	case "message":
+		return part(func(m syslogMessage) string { return m.message }), nil
+	case "facility":
+		return part(func(m syslogMessage) string {
+			if m.pri < 0 {
+				return ""
+			}
+			return syslogFacilities[m.pri/8]
+		}), nil
+	case "severity":
+		return part(func(m syslogMessage) string {
+			if m.pri < 0 {
+				return ""
+			}
+			return syslogSeverities[m.pri%8]
+		}), nil
+	case "sd":
+		return func(m syslogMessage) []string {
+			var ids []string
+			for _, element := range sdElementRegex.FindAllStringSubmatch(m.structuredData, -1) {
+				ids = append(ids, element[1])
+			}
+			return ids
+		}, nil
+	}
+
+	if strings.HasPrefix(field, "sd:") {
+		id, name := "", field[len("sd:"):]
+		if i := strings.LastIndex(name, ":"); i >= 0 {
+			id, name = name[:i], name[i+1:]
+		}
+		return func(m syslogMessage) []string {
+			var values []string
+			for _, element := range sdElementRegex.FindAllStringSubmatch(m.structuredData, -1) {
+				if id != "" && element[1] != id {
+					continue
+				}
+				for _, param := range sdParamRegex.FindAllStringSubmatch(element[2], -1) {
+					if param[1] == name {
+						values = append(values, sdUnescaper.Replace(param[2]))
+					}
+				}
+			}
+			return values
+		}, nil
+	}
+
+	return nil, fmt.Errorf("syslog has no %s field", field)
+}
@@ -0,0 +1,72 @@
+package tokenize
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+const syslogInput = `<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - 'su root' failed for lonvick on /dev/pts/8
+<165>1 2003-10-11T22:14:15.003Z host2 evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication"][examplePriority@32473 class="high"] An application event
+<13>Oct 11 22:14:15 mymachine su[230]: 'su root' failed
+Jan  2 09:07:50 host2 kernel: [142597.045021] usb
+2012-01-03  08:17:01  CRON[14610]: (root) CMD
+2012-01-04  09:38:19  AptDaemon: INFO: Initializing
+2017-06-01T12:00:00.123456+00:00 host3 CRON[99]: (root) CMD
+nothing to see here
+`
+
+func TestSyslogTokenizer_Tokenize(t *testing.T) {
+	testCases := []struct {
+		field    string
+		matcher  string
+		expected map[string]float64
+	}{
+		{"", ".", map[string]float64{"su": 2, "evntslog": 1, "kernel": 1, "CRON": 2, "AptDaemon": 1}},
+		{"host", ".", map[string]float64{"mymachine.example.com": 1, "mymachine": 1, "host2": 2, "host3": 1}},
+		{"pid", ".", map[string]float64{"1234": 1, "230": 1, "14610": 1, "99": 1}},
+		{"msgid", ".", map[string]float64{"ID47": 2}},
+		{"facility", ".", map[string]float64{"auth": 1, "local4": 1, "user": 1}},
+		{"severity", ".", map[string]float64{"crit": 1, "notice": 2}},
+		{"message", "^'su", map[string]float64{"'su root' failed for lonvick on /dev/pts/8": 1, "'su root' failed": 1}},
+		{"timestamp", "^Jan", map[string]float64{"Jan  2 09:07:50": 1}},
+		{"sd", ".", map[string]float64{"exampleSDID@32473": 1, "examplePriority@32473": 1}},
+		{"sd:eventSource", ".", map[string]float64{`App"lication`: 1}},
+		{"sd:examplePriority@32473:class", ".", map[string]float64{"high": 1}},
+		{"sd:exampleSDID@32473:class", ".", map[string]float64{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.field, func(t *testing.T) {
+			s, err := NewSyslogTokenizer(LogFields{Key: tc.field}, tc.matcher)
+			if err != nil {
+				t.Fatalf("NewSyslogTokenizer returned an error: %s", err)
+			}
+			actual, err := s.Tokenize(bytes.NewBufferString(syslogInput))
+			if err != nil {
+				t.Fatalf("Tokenize returned an error: %s", err)
+			}
+			if fmt.Sprint(actual) != fmt.Sprint(tc.expected) {
+				t.Errorf("Tokenize incorrect: expected %v; actual %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSyslogTokenizer_Distinct(t *testing.T) {
+	s, err := NewSyslogTokenizer(LogFields{Key: "app-name", Distinct: "host"}, ".")
+	if err != nil {
+		t.Fatalf("NewSyslogTokenizer returned an error: %s", err)
+	}
+	actual, _ := s.Tokenize(bytes.NewBufferString(syslogInput))
+	expected := map[string]float64{"su": 2, "evntslog": 1, "kernel": 1, "CRON": 1}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("Tokenize incorrect: expected %v; actual %v", expected, actual)
+	}
+}
+
+func TestNewSyslogTokenizer_UnknownField(t *testing.T) {
+	if _, err := NewSyslogTokenizer(LogFields{Key: "path"}, "."); err == nil {
+		t.Error("NewSyslogTokenizer should reject a field syslog does not have")
+	}
+}
@@ -1,66 +1,156 @@
 package tokenize
 
 import (
-	"bufio"
 	"io"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
+// Tokenizer counts the keys in its input. A Tokenizer that is interrupted
+// (see WithContext) returns what it had counted along with ErrInterrupted.
 type Tokenizer interface {
-	Tokenize(io.Reader) (map[string]uint, error)
+	Tokenize(io.Reader) (map[string]float64, error)
 }
 
-type preTalliedTokenizer struct {
+const (
+	KEY_VALUE_REGEX = `^\s*(.+)\s+(-?\d+(?:\.\d+)?)$`
+	VALUE_KEY_REGEX = `^\s*(-?\d+(?:\.\d+)?)\s+(.+)$`
+)
+
+// NewKeyValueTokenizer returns a Tokenizer for input that is already tallied,
+// a key then its value on each line. The last value for a key is the one kept
+// unless given WithCounter.
+func NewKeyValueTokenizer(opts ...Option) Tokenizer {
+	return newPreTalliedTokenizer(extractShortcuts["kv"], opts)
+}
+
+// NewValueKeyTokenizer is NewKeyValueTokenizer for a value then its key on
+// each line
+func NewValueKeyTokenizer(opts ...Option) Tokenizer {
+	return newPreTalliedTokenizer(extractShortcuts["vk"], opts)
+}
+
+func newPreTalliedTokenizer(extractor string, opts []Option) Tokenizer {
+	opts = append([]Option{WithCounter(NewLastCounter)}, opts...)
+	return NewPipeline(opts...).Extract(RegexpExtractor(regexp.MustCompile(extractor)))
+}
+
+type multiValueTokenizer struct {
+	options
 	extractor *regexp.Regexp
-	keyIdx    int
-	valueIdx  int
 }
 
-const (
-	KEY_VALUE_REGEX = `^\s*(.+)\s+(\d+)$`
-	VALUE_KEY_REGEX = `^\s*(\d+)\s+(.+)$`
-)
+const MULTI_VALUE_REGEX = `^\s*(.+?)((?:\s+-?\d+(?:\.\d+)?)+)\s*$`
+
+// multiValueNumber is one of the values of MULTI_VALUE_REGEX
+var multiValueNumber = regexp.MustCompile(`^-?\d+(?:\.\d+)?$`)
 
-func NewKeyValueTokenizer() Tokenizer {
-	return preTalliedTokenizer{
-		extractor: regexp.MustCompile(KEY_VALUE_REGEX),
-		keyIdx:    1,
-		valueIdx:  2,
+// NewMultiValueTokenizer returns a PairTokenizer for pre-tallied input with
+// several values after each key, one for each series. If the first line has no
+// values, or starts with "#" so that the series may have numbers for names,
+// it is a header: its fields after the first name the series. Otherwise the
+// series are numbered from 1, as many as the first line has values. Lines
+// without a key and a value for each series are skipped.
+func NewMultiValueTokenizer(opts ...Option) PairTokenizer {
+	return multiValueTokenizer{
+		options:   newOptions(opts),
+		extractor: regexp.MustCompile(MULTI_VALUE_REGEX),
 	}
 }
 
-func NewValueKeyTokenizer() Tokenizer {
-	return preTalliedTokenizer{
-		extractor: regexp.MustCompile(VALUE_KEY_REGEX),
-		keyIdx:    2,
-		valueIdx:  1,
+func (m multiValueTokenizer) TokenizePairs(reader io.Reader) (*PairCounts, error) {
+	pairCounts := NewPairCounts()
+	var series []string
+	// columns is the number of values on each line of data, once known
+	columns := 0
+
+	first := true
+	err := m.scan(reader, func() int { return len(pairCounts.Counts) }, func(line string) (int, int, error) {
+		if first {
+			first = false
+			if names, ok := multiValueHeader(line, m.extractor); ok {
+				series = names
+				columns = len(series)
+				return 1, 0, nil
+			}
+		}
+
+		var key string
+		var values []string
+		if columns == 0 {
+			res := m.extractor.FindStringSubmatch(line)
+			if res == nil {
+				return 1, 0, nil
+			}
+			// without a header, the first line of data says how many series
+			// there are
+			key, values = res[1], strings.Fields(res[2])
+			columns = len(values)
+		} else {
+			var ok bool
+			if key, values, ok = lastValues(line, columns); !ok {
+				return 1, 0, nil
+			}
+		}
+
+		for i, field := range values {
+			value, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return 1, 0, err
+			}
+			name := strconv.Itoa(i + 1)
+			if i < len(series) {
+				name = series[i]
+			}
+			pairCounts.Add(key, name, value)
+		}
+		return 1, 1, nil
+	})
+	if err != nil && err != ErrInterrupted {
+		return nil, err
 	}
-}
 
-func (p preTalliedTokenizer) Tokenize(reader io.Reader) (map[string]uint, error) {
-	tokenCounts := make(map[string]uint)
+	return pairCounts, err
+}
 
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		line := scanner.Text()
-		res := p.extractor.FindStringSubmatch(line)
-		key := res[p.keyIdx]
-		value, err := strconv.ParseUint(res[p.valueIdx], 10, 32)
-		if err != nil {
-			return nil, err
+// lastValues splits line into a key and the n values after it, so that
+// numbers at the end of a key are left in it. It fails if there is no key or
+// any of the values is not a number.
+func lastValues(line string, n int) (string, []string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) <= n {
+		return "", nil, false
+	}
+	values := fields[len(fields)-n:]
+	for _, v := range values {
+		if !multiValueNumber.MatchString(v) {
+			return "", nil, false
 		}
-		tokenCounts[key] = uint(value)
 	}
 
-	return tokenCounts, nil
+	key := strings.TrimSpace(line)
+	for range values {
+		key = strings.TrimRightFunc(key[:strings.LastIndexFunc(key, unicode.IsSpace)+1], unicode.IsSpace)
+	}
+	return key, values, true
 }
 
-type regexTokenizer struct {
-	splitter         *regexp.Regexp
-	matcher          *regexp.Regexp
-	keyPruneInterval int
+// multiValueHeader returns the names of the series if line is a header: one
+// that starts with "#", or that has no values after its first field
+func multiValueHeader(line string, extractor *regexp.Regexp) ([]string, bool) {
+	trimmed := strings.TrimSpace(line)
+	commented := strings.HasPrefix(trimmed, "#")
+	if !commented && extractor.MatchString(line) {
+		return nil, false
+	}
+	fields := strings.Fields(strings.TrimPrefix(trimmed, "#"))
+	if len(fields) < 2 {
+		return nil, false
+	}
+	return fields[1:], true
 }
 
 const (
@@ -70,74 +160,115 @@ const (
 	NUM_MATCH_REGEX  = `^\d+$`
 )
 
-func NewRegexTokenizer(splitter string, matcher string) Tokenizer {
-	t := &regexTokenizer{}
-
-	switch splitter {
-	case "white":
-		t.splitter = regexp.MustCompile(WHITESPACE_REGEX)
-	case "word":
-		t.splitter = regexp.MustCompile(WORD_SPLIT_REGEX)
-	default:
-		t.splitter = regexp.MustCompile(splitter)
+// NewRegexTokenizer returns a Tokenizer that splits lines at matches of the
+// splitter regexp, or into the matches of a named pattern, and counts the
+// tokens that match matcher. Either regexp may instead name a pattern, as
+// "@ipv4".
+func NewRegexTokenizer(splitter string, matcher string, opts ...Option) (Tokenizer, error) {
+	split, err := compileSplitter(splitter)
+	if err != nil {
+		return nil, err
 	}
-
-	switch matcher {
-	case "word":
-		t.matcher = regexp.MustCompile(WORD_MATCH_REGEX)
-	case "num":
-		t.matcher = regexp.MustCompile(NUM_MATCH_REGEX)
-	default:
-		t.matcher = regexp.MustCompile(matcher)
+	re, err := compileMatcher(matcher)
+	if err != nil {
+		return nil, err
 	}
 
-	return t
+	return NewPipeline(opts...).Split(split).Filter(MatchKey(re)), nil
 }
 
-func (r *regexTokenizer) Tokenize(reader io.Reader) (map[string]uint, error) {
-	tokenCounts := make(map[string]uint)
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		line := strings.TrimRight(scanner.Text(), "\n")
-		for _, token := range r.splitter.Split(line, -1) {
-			if r.matcher.MatchString(token) {
-				tokenCounts[token]++
-			}
-		}
+// NewLineTokenizer returns a Tokenizer that counts the lines that match
+// matcher
+func NewLineTokenizer(matcher string, opts ...Option) (Tokenizer, error) {
+	re, err := compileMatcher(matcher)
+	if err != nil {
+		return nil, err
 	}
+	return NewPipeline(opts...).Filter(MatchKey(re)), nil
+}
 
-	return tokenCounts, nil
+type numericTokenizer struct {
+	options
+	differences bool
+	rate        bool
 }
 
-type lineTokenizer struct {
-	matcher          *regexp.Regexp
-	keyPruneInterval int
+// NewNumericTokenizer returns a Tokenizer for input of one number per line,
+// as for --numonly. Numbers may be negative or have a fractional part. Each
+// number is keyed by its position in the input.
+//
+// In "mon" mode the numbers are readings of a counter, and the values are the
+// differences between successive readings. The first reading is only a
+// starting point. A reading lower than the one before means the counter was
+// reset, so the difference is the reading itself: what has been counted since
+// the restart.
+//
+// With rate, each line is a timestamp followed by a reading, the values are
+// the differences per second between readings, and each is keyed by the
+// timestamp of the later reading. Rate implies "mon".
+func NewNumericTokenizer(mode string, rate bool, opts ...Option) Tokenizer {
+	return numericTokenizer{options: newOptions(opts), differences: mode == "mon" || rate, rate: rate}
 }
 
-func NewLineTokenizer(matcher string) Tokenizer {
-	t := &lineTokenizer{}
+func (n numericTokenizer) Tokenize(reader io.Reader) (map[string]float64, error) {
+	tokenCounts := make(map[string]float64)
+	var last float64
+	var lastTime time.Time
+	seen := false
+	seq := 0
 
-	switch matcher {
-	case "word":
-		t.matcher = regexp.MustCompile(WORD_MATCH_REGEX)
-	case "num":
-		t.matcher = regexp.MustCompile(NUM_MATCH_REGEX)
-	default:
-		t.matcher = regexp.MustCompile(matcher)
-	}
+	err := n.scan(reader, func() int { return len(tokenCounts) }, func(line string) (int, int, error) {
+		line = strings.TrimSpace(line)
 
-	return t
-}
+		var key string
+		var when time.Time
+		if n.rate {
+			i := strings.LastIndexAny(line, " \t")
+			if i < 0 {
+				return 1, 0, nil
+			}
+			var ok bool
+			key = strings.TrimSpace(line[:i])
+			when, ok = parseTimestamp(key)
+			if !ok {
+				return 1, 0, nil
+			}
+			line = line[i+1:]
+		}
+
+		value, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			return 1, 0, nil
+		}
+
+		if n.differences {
+			previous, previousTime, first := last, lastTime, !seen
+			last, lastTime, seen = value, when, true
+			if first {
+				return 1, 0, nil
+			}
+			if value >= previous {
+				value -= previous
+			}
+			if n.rate {
+				elapsed := when.Sub(previousTime).Seconds()
+				if elapsed <= 0 {
+					return 1, 0, nil
+				}
+				value /= elapsed
+			}
+		}
 
-func (l lineTokenizer) Tokenize(reader io.Reader) (map[string]uint, error) {
-	tokenCounts := make(map[string]uint)
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		line := strings.TrimRight(scanner.Text(), "\n")
-		if l.matcher.MatchString(line) {
-			tokenCounts[line]++
+		seq++
+		if key == "" {
+			key = strconv.Itoa(seq)
 		}
+		tokenCounts[key] = value
+		return 1, 1, nil
+	})
+	if err != nil && err != ErrInterrupted {
+		return nil, err
 	}
 
-	return tokenCounts, nil
+	return tokenCounts, err
 }
@@ -3,6 +3,7 @@ package tokenize
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -23,6 +24,12 @@ func TestKeyValueTokenizer_Tokenize(t *testing.T) {
 	if v, ok := tc["a"]; !ok || v != 1 {
 		t.Error("Tokenize did not extract key/value correctly")
 	}
+
+	buf.WriteString("loss -2.5\n")
+	tc, _ = kv.Tokenize(buf)
+	if v, ok := tc["loss"]; !ok || v != -2.5 {
+		t.Error("Tokenize did not extract a negative value correctly")
+	}
 }
 
 func TestValueKeyTokenizer_Tokenize(t *testing.T) {
@@ -44,6 +51,108 @@ func TestValueKeyTokenizer_Tokenize(t *testing.T) {
 	}
 }
 
+func TestMultiValueTokenizer_TokenizePairs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		series   []string
+		expected map[string]map[string]float64
+	}{
+		{"Empty", "", nil, map[string]map[string]float64{}},
+		{"Numbered series", "/a 12 1\n/b 6 3\n", []string{"1", "2"}, map[string]map[string]float64{
+			"/a": {"1": 12, "2": 1},
+			"/b": {"1": 6, "2": 3},
+		}},
+		{"Header names series", "path requests errors\n/a 12 1\n/b c 6 3\n", []string{"requests", "errors"}, map[string]map[string]float64{
+			"/a":   {"requests": 12, "errors": 1},
+			"/b c": {"requests": 6, "errors": 3},
+		}},
+		{"Numbers in keys", "path requests errors\n/a 2 12 1\n/b 6 3\n", []string{"requests", "errors"}, map[string]map[string]float64{
+			"/a 2": {"requests": 12, "errors": 1},
+			"/b":   {"requests": 6, "errors": 3},
+		}},
+		{"Numbered series keep their count", "/a 12 1\n/b 2 6 3\n/c 4\n", []string{"1", "2"}, map[string]map[string]float64{
+			"/a":   {"1": 12, "2": 1},
+			"/b 2": {"1": 6, "2": 3},
+		}},
+		{"Header of numbers", "# host 2016 2017\nweb 4 5\n", []string{"2016", "2017"}, map[string]map[string]float64{
+			"web": {"2016": 4, "2017": 5},
+		}},
+		{"Spacing in keys kept", "/a  b 1\n/c\t d  2 \n", []string{"1"}, map[string]map[string]float64{
+			"/a  b":  {"1": 1},
+			"/c\t d": {"1": 2},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewMultiValueTokenizer()
+			pc, err := m.TokenizePairs(bytes.NewBufferString(tc.input))
+			if err != nil {
+				t.Fatalf("TokenizePairs returned an error: %s", err)
+			}
+			if fmt.Sprint(pc.Secondaries) != fmt.Sprint(tc.series) {
+				t.Errorf("TokenizePairs series incorrect: expected %v; actual %v", tc.series, pc.Secondaries)
+			}
+			if fmt.Sprint(pc.Counts) != fmt.Sprint(tc.expected) {
+				t.Errorf("TokenizePairs counts incorrect: expected %v; actual %v", tc.expected, pc.Counts)
+			}
+		})
+	}
+}
+
+func TestMultiValueTokenizer_ManySeries(t *testing.T) {
+	values := strings.Repeat(" 1", 1001)
+	input := "#key" + values + "\n/a" + values + "\n/b" + values + "\n"
+
+	pc, err := NewMultiValueTokenizer().TokenizePairs(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatalf("TokenizePairs returned an error: %s", err)
+	}
+	if len(pc.Counts) != 2 || len(pc.Counts["/a"]) != 1 || pc.Counts["/a"]["1"] != 1001 {
+		t.Errorf("TokenizePairs incorrect for 1001 series: counts of /a %v", pc.Counts["/a"]["1"])
+	}
+
+	pc, err = NewMultiValueTokenizer().TokenizePairs(bytes.NewBufferString("/a" + values + "\n/b" + values + "\n"))
+	if err != nil {
+		t.Fatalf("TokenizePairs returned an error: %s", err)
+	}
+	if len(pc.Counts) != 2 || len(pc.Counts["/b"]) != 1001 {
+		t.Errorf("TokenizePairs incorrect for 1001 numbered series: %d keys", len(pc.Counts))
+	}
+}
+
+func TestNumericTokenizer_Tokenize(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mode     string
+		rate     bool
+		input    string
+		expected map[string]float64
+	}{
+		{"Empty", "abs", false, "", map[string]float64{}},
+		{"Absolute", "abs", false, "5\n x\n 7 \n5\n", map[string]float64{"1": 5, "2": 7, "3": 5}},
+		{"Signed", "abs", false, "-2.5\n+3\n", map[string]float64{"1": -2.5, "2": 3}},
+		{"Differences", "mon", false, "10\n12\n17\n17\n", map[string]float64{"1": 2, "2": 5, "3": 0}},
+		{"Counter reset", "mon", false, "10\n12\n3\n8\n", map[string]float64{"1": 2, "2": 3, "3": 5}},
+		{"Rate", "abs", true, "100 10\n110 30\n130 35\nbad 40\n140 50\n", map[string]float64{"110": 2, "130": 0.25, "140": 1.5}},
+		{"Rate timestamps", "mon", true, "2017-06-01 12:00:00 5\n2017-06-01 12:00:10 105\n", map[string]float64{"2017-06-01 12:00:10": 10}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			n := NewNumericTokenizer(tc.mode, tc.rate)
+			actual, err := n.Tokenize(bytes.NewBufferString(tc.input))
+			if err != nil {
+				t.Fatalf("Tokenize returned an error: %s", err)
+			}
+			if fmt.Sprint(actual) != fmt.Sprint(tc.expected) {
+				t.Errorf("Tokenize incorrect: expected %v; actual %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
 func TestNewRegexTokenizer(t *testing.T) {
 	testCases := []struct {
 		splitter string
@@ -62,16 +171,36 @@ func TestNewRegexTokenizer(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(fmt.Sprintf("spliter: %s; matcher: %s", tc.splitter, tc.matcher), func(t *testing.T) {
-			r := NewRegexTokenizer(tc.splitter, tc.matcher)
-			if r == nil {
-				t.Error("Unable to create regexTokenizer w/ shortcuts")
+			r, err := NewRegexTokenizer(tc.splitter, tc.matcher)
+			if r == nil || err != nil {
+				t.Errorf("Unable to create regexTokenizer w/ shortcuts: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewRegexTokenizer_Errors(t *testing.T) {
+	testCases := []struct {
+		splitter string
+		matcher  string
+		expected string
+	}{
+		{`(`, "word", "split regexp: error parsing regexp: missing closing ): `(`"},
+		{"white", `[a-`, "match regexp: error parsing regexp: missing closing ]: `[a-`"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("spliter: %s; matcher: %s", tc.splitter, tc.matcher), func(t *testing.T) {
+			_, err := NewRegexTokenizer(tc.splitter, tc.matcher)
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("NewRegexTokenizer error incorrect: expected %v; actual %v", tc.expected, err)
 			}
 		})
 	}
 }
 
 func TestRegexTokenizer_Tokenize(t *testing.T) {
-	r := NewRegexTokenizer("white", "word")
+	r, _ := NewRegexTokenizer("white", "word")
 	buf := new(bytes.Buffer)
 
 	tc, _ := r.Tokenize(buf)
@@ -124,16 +253,16 @@ func TestNewLineTokenizer(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(fmt.Sprintf("matcher: %s", tc.matcher), func(t *testing.T) {
-			l := NewLineTokenizer(tc.matcher)
-			if l == nil {
-				t.Error("Unable to create lineTokenizer w/ shortcuts")
+			l, err := NewLineTokenizer(tc.matcher)
+			if l == nil || err != nil {
+				t.Errorf("Unable to create lineTokenizer w/ shortcuts: %v", err)
 			}
 		})
 	}
 }
 
 func TestLineTokenizer_Tokenize(t *testing.T) {
-	l := NewLineTokenizer(".")
+	l, _ := NewLineTokenizer(".")
 	buf := new(bytes.Buffer)
 
 	tc, _ := l.Tokenize(buf)